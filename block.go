@@ -0,0 +1,414 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unioffice/schema/soo/dml/spreadsheetDrawing"
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// placeholderBlock is a block of template rows kept or removed by a condition, which is marked by
+// {{if .HasAttachments}} in any cell of the first row and {{end}} in any cell of the last row,
+// the rows between are kept when the field is true, or false for {{if not .HasAttachments}}.
+// The {{end}} in a loop row ends the loop instead of the block.
+type placeholderBlock struct {
+	Start, End uint32
+	// Name is the placeholder name of the condition field.
+	Name string
+	// Not tells the condition is negated like {{if not .HasAttachments}}.
+	Not bool
+}
+
+// parseIfVar parses the condition name from the placeholder var like "if .Name" or "if not .Name".
+func parseIfVar(v string) (name string, not, ok bool) {
+	fields := strings.Fields(v)
+
+	switch {
+	case len(fields) == 2 && fields[0] == "if": // nolint:gomnd
+		return strings.TrimPrefix(fields[1], "."), false, true
+	case len(fields) == 3 && fields[0] == "if" && fields[1] == "not": // nolint:gomnd
+		return strings.TrimPrefix(fields[2], "."), true, true
+	default:
+		return "", false, false
+	}
+}
+
+// isRowMarker tells the var is the marker of the loops or the blocks like range .Items, if .Name or end.
+func isRowMarker(v string) bool {
+	_, ok := parseRangeVar(v)
+	_, _, isIf := parseIfVar(v)
+
+	return ok || isIf || v == "end"
+}
+
+// collectPlaceholderBlocks collects the conditional blocks of the sheet ordered by the start rows,
+// the blocks can be nested, and the unclosed blocks are ignored.
+func (x *Xlsx) collectPlaceholderBlocks(sheet spreadsheet.Sheet) []placeholderBlock {
+	blocks := make([]placeholderBlock, 0)
+	open := make([]placeholderBlock, 0)
+
+	for _, row := range sheet.Rows() {
+		parts := make([]PlaceholderPart, 0)
+		loopRow := false
+
+		for _, cell := range x.rowCells(row) {
+			for _, p := range x.parsePlaceholder(GetCellString(cell)).Parts {
+				_, isRange := parseRangeVar(p.Var)
+				loopRow = loopRow || isRange
+				parts = append(parts, p)
+			}
+		}
+
+		for _, p := range parts {
+			if name, not, ok := parseIfVar(p.Var); ok {
+				open = append(open, placeholderBlock{Start: row.RowNumber(), Name: name, Not: not})
+			} else if p.Var == "end" && !loopRow && len(open) > 0 {
+				b := open[len(open)-1]
+				b.End, open = row.RowNumber(), open[:len(open)-1]
+				blocks = append(blocks, b)
+			}
+		}
+	}
+
+	for _, b := range open {
+		x.logger().Warn("unable to find end for block", "if", b.Name, "row", b.Start)
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+
+	return blocks
+}
+
+// removeBlockMarkers removes the {{if .Name}} and the {{end}} markers of the blocks from the placeholder value,
+// the {{end}} markers of the loop rows are kept for the loops.
+func (x *Xlsx) removeBlockMarkers(pl PlaceholderValue) PlaceholderValue {
+	content := pl.Content
+
+	for _, p := range pl.Parts {
+		if _, _, ok := parseIfVar(p.Var); ok || p.Var == "end" {
+			content = strings.Replace(content, p.Part, "", 1)
+		}
+	}
+
+	return x.parsePlaceholder(content)
+}
+
+// writePlaceholderBlocks removes the blocks with the false conditions and the block markers of the kept blocks,
+// the rows below are shifted up with their heights, merged cells and images.
+func (x *Xlsx) writePlaceholderBlocks(fields []reflect.StructField, v reflect.Value) {
+	sheet := x.currentSheet
+	blocks := x.collectPlaceholderBlocks(sheet)
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		b := blocks[i]
+
+		for _, rowNum := range []uint32{b.Start, b.End} {
+			for _, cell := range x.rowCells(sheet.Row(rowNum)) {
+				pl := x.parsePlaceholder(GetCellString(cell))
+				if stripped := x.removeBlockMarkers(pl); stripped.Content != pl.Content {
+					cell.SetString(stripped.Content)
+				}
+			}
+		}
+	}
+
+	removed := make(loopRowShifts, 0)
+
+	for _, b := range blocks {
+		if removed.removes(b.Start) || x.blockCondition(fields, v, b) {
+			continue
+		}
+
+		n := b.End - b.Start + 1
+		removed = append(removed, rowShift{afterRow: b.End, n: -int(n)})
+	}
+
+	for i := len(removed) - 1; i >= 0; i-- {
+		n := uint32(-removed[i].n)
+		removeRows(x.workbook, sheet, removed[i].afterRow-n+1, n)
+	}
+}
+
+// blockCondition evaluates the condition of the block by the field value like text/template,
+// the false, 0, nil and the empty string, slice and map are false.
+func (x *Xlsx) blockCondition(fields []reflect.StructField, v reflect.Value, b placeholderBlock) bool {
+	fv, ok := placeholderFieldValue(fields, v, b.Name)
+	if !ok {
+		x.logger().Warn("unable to find field for block", "if", b.Name)
+	}
+
+	return isTrue(fv) != b.Not
+}
+
+// placeholderFieldValue finds the field value by the placeholder name, or the path like Owner.Name.
+func placeholderFieldValue(fields []reflect.StructField, v reflect.Value, name string) (reflect.Value, bool) {
+	for _, f := range fields {
+		fieldName := placeholderName(f)
+		if fieldName == name {
+			return v.FieldByIndex(f.Index), true
+		}
+
+		if !strings.HasPrefix(name, fieldName+".") {
+			continue
+		}
+
+		if nested, ok := nestedStruct(f, v); ok {
+			if !nested.IsValid() {
+				return reflect.Value{}, true
+			}
+
+			return placeholderFieldValue(exportedFields(nested.Type()), nested, name[len(fieldName)+1:])
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func isTrue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil() && isTrue(v.Elem())
+	case reflect.Struct:
+		return v.Type() != timeType || !v.IsZero()
+	default:
+		return !v.IsZero()
+	}
+}
+
+// removeRows removes n rows from the row fromRow, and shifts the rows below up with the merged cells,
+// the images anchored to the cells, and the references to the rows like the formulas,
+// the data validations and the defined names, the references to the removed rows become #REF!.
+func removeRows(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet, fromRow, n uint32) {
+	toRow := fromRow + n - 1
+	rows := sheet.X().SheetData.Row[:0]
+
+	for _, row := range sheet.X().SheetData.Row {
+		if row.RAttr != nil && *row.RAttr >= fromRow && *row.RAttr <= toRow {
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	sheet.X().SheetData.Row = rows
+	shiftRowsUp(sheet, toRow, n)
+
+	s := rowShift{afterRow: toRow, n: -int(n)}
+	shiftReferences(wb, sheet, s)
+	shiftDrawing(sheet, s)
+}
+
+// shiftRowsUp shifts the rows after the row afterRow up by n rows, the merged cells inside the removed rows
+// are removed, and the merged cells across the removed rows are shrunk.
+func shiftRowsUp(sheet spreadsheet.Sheet, afterRow, n uint32) {
+	for _, row := range sheet.X().SheetData.Row {
+		if row.RAttr == nil || *row.RAttr <= afterRow {
+			continue
+		}
+
+		rowNum := *row.RAttr - n
+		row.RAttr = &rowNum
+
+		for _, c := range row.C {
+			if c.RAttr == nil {
+				continue
+			}
+
+			if ref, err := reference.ParseCellReference(*c.RAttr); err == nil {
+				r := fmt.Sprintf("%s%d", ref.Column, ref.RowIdx-n)
+				c.RAttr = &r
+			}
+		}
+	}
+
+	shiftMergedCellsUp(sheet, afterRow, n)
+}
+
+func shiftMergedCellsUp(sheet spreadsheet.Sheet, afterRow, n uint32) {
+	mcs := sheet.X().MergeCells
+	if mcs == nil {
+		return
+	}
+
+	fromRow := afterRow - n + 1
+	merged := mcs.MergeCell[:0]
+
+	for _, mc := range mcs.MergeCell {
+		from, to, err := reference.ParseRangeReference(mc.RefAttr)
+		if err != nil || to.RowIdx < fromRow {
+			merged = append(merged, mc)
+			continue
+		}
+
+		from.RowIdx = shiftRowUp(from.RowIdx, afterRow, n, fromRow)
+		to.RowIdx = shiftRowUp(to.RowIdx+1, afterRow, n, fromRow) - 1
+
+		if to.RowIdx < from.RowIdx || (from.RowIdx == to.RowIdx && from.ColumnIdx == to.ColumnIdx) {
+			continue
+		}
+
+		mc.RefAttr = fmt.Sprintf("%s%d:%s%d", from.Column, from.RowIdx, to.Column, to.RowIdx)
+		merged = append(merged, mc)
+	}
+
+	mcs.MergeCell = merged
+
+	if len(merged) == 0 {
+		sheet.X().MergeCells = nil
+	} else {
+		mcs.CountAttr = nil
+	}
+}
+
+// shiftRowUp returns the row number after the rows from fromRow to afterRow are removed,
+// the rows inside the removed rows are moved to the first row below.
+func shiftRowUp(rowNum, afterRow, n, fromRow uint32) uint32 {
+	switch {
+	case rowNum > afterRow:
+		return rowNum - n
+	case rowNum >= fromRow:
+		return fromRow
+	default:
+		return rowNum
+	}
+}
+
+func anchorMarkers(anchor *spreadsheetDrawing.EG_Anchor) (from, to *spreadsheetDrawing.CT_Marker) {
+	switch {
+	case anchor.TwoCellAnchor != nil:
+		return anchor.TwoCellAnchor.From, anchor.TwoCellAnchor.To
+	case anchor.OneCellAnchor != nil:
+		return anchor.OneCellAnchor.From, nil
+	default:
+		return nil, nil
+	}
+}
+
+// matchBlock tells the block is kept in the sheet read, by matching the template cells of the block rows
+// before the first loop row with the rows at the shifted row numbers, or the first loop row when the block
+// starts with the loop. The block is taken as kept when it has no cells to match.
+func (x *Xlsx) matchBlock(b placeholderBlock, loops []placeholderLoop, fields []reflect.StructField,
+	shifts loopRowShifts, rows map[uint32]spreadsheet.Row,
+) (bool, error) {
+	tmplRows := make(map[uint32]spreadsheet.Row)
+	for _, row := range x.tmplSheet.Rows() {
+		tmplRows[row.RowNumber()] = row
+	}
+
+	for rowNum := b.Start; rowNum <= b.End; rowNum++ {
+		row := rows[shifts.shift(rowNum)]
+
+		if l, ok := findLoop(loops, rowNum); ok {
+			if rowNum > b.Start {
+				return true, nil
+			}
+
+			f, _ := findPlaceholderField(fields, l.Name)
+			_, matched, err := x.matchLoopRow(l, row, loopFieldTypes(reflect.Zero(f.Type)))
+
+			return row.X() != nil && matched, err
+		}
+
+		if tmplRow, ok := tmplRows[rowNum]; ok {
+			if matched, err := x.matchBlockRow(tmplRow, row); err != nil || !matched {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// matchBlockRow tells the row matches the template row, the texts are equal and the placeholders are matched,
+// and there is no text out of the template cells.
+func (x *Xlsx) matchBlockRow(tmplRow, row spreadsheet.Row) (bool, error) {
+	columns := make(map[string]bool)
+
+	var matchErr error
+
+	for _, cell := range x.rowCells(tmplRow) {
+		col, err := cell.Column()
+		if err != nil {
+			continue
+		}
+
+		pl := x.removeBlockMarkers(x.parsePlaceholder(GetCellString(cell)))
+		if pl.Content == "" {
+			continue
+		}
+
+		columns[col] = true
+		s := ""
+
+		if row.X() != nil {
+			s = x.getCellString(row.Cell(col))
+		}
+
+		if !pl.HasPlaceholders() && s != pl.Interpolate(nil) {
+			return false, nil
+		}
+
+		if _, ok, err := matchPlaceholder(pl, s); err != nil {
+			matchErr = firstError(matchErr, err)
+		} else if !ok {
+			return false, nil
+		}
+	}
+
+	if row.X() == nil {
+		return len(columns) == 0, nil
+	}
+
+	for _, cell := range x.rowCells(row) {
+		if col, err := cell.Column(); err == nil && !columns[col] && GetCellString(cell) != "" {
+			return false, nil
+		}
+	}
+
+	return matchErr == nil, matchErr
+}
+
+func findLoop(loops []placeholderLoop, rowNum uint32) (placeholderLoop, bool) {
+	for _, l := range loops {
+		if l.RowNum == rowNum {
+			return l, true
+		}
+	}
+
+	return placeholderLoop{}, false
+}
+
+// readBlockVars sets the vars of the bool condition fields by the blocks kept or removed in the sheet read,
+// the blocks inside the removed blocks are skipped.
+func readBlockVars(blocks []placeholderBlock, shifts loopRowShifts,
+	fieldTypes map[string]reflect.StructField, vars map[string]string,
+) {
+	for _, b := range blocks {
+		removed := false
+
+		for _, s := range shifts {
+			removed = removed || (s.afterRow == b.End && s.n == -int(b.End-b.Start+1))
+		}
+
+		if !removed && shifts.removes(b.Start) {
+			continue
+		}
+
+		if _, ok := vars[b.Name]; ok || fieldTypes[b.Name].Type == nil || fieldTypes[b.Name].Type.Kind() != reflect.Bool {
+			continue
+		}
+
+		vars[b.Name] = strconv.FormatBool(removed == b.Not)
+	}
+}
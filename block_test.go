@@ -0,0 +1,139 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/measurement"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type attachment struct {
+	Name  string
+	Pages int
+}
+
+type contract struct {
+	ContractNo     string `asPlaceholder:"true"`
+	HasAttachments bool
+	Attachments    []attachment
+	Note           string
+	Signer         string
+}
+
+func createContractTemplate(t *testing.T) []byte {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("合同：{{ContractNo}}")
+	sheet.Cell("A2").SetString("{{if .HasAttachments}}附件清单")
+	sheet.AddMergedCells("A2", "C2")
+	sheet.Cell("A3").SetString("{{range .Attachments}}{{.Name}}")
+	sheet.Cell("B3").SetString("{{.Pages}}")
+	sheet.Cell("A4").SetString("附件说明：{{Note}}{{end}}")
+	sheet.Cell("A5").SetString("{{if not .HasAttachments}}无附件{{end}}")
+	sheet.Cell("A6").SetString("签字：{{Signer}}")
+	sheet.AddMergedCells("A6", "C6")
+	sheet.Row(6).SetHeight(40 * measurement.Point)
+
+	return saveWorkbook(t, wb)
+}
+
+func writeContract(t *testing.T, tmpl []byte, c contract) (spreadsheet.Sheet, []byte) {
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(c))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	return out.Sheets()[0], buf.Bytes()
+}
+
+func mergedRefs(sheet spreadsheet.Sheet) []string {
+	refs := make([]string, 0)
+	for _, mc := range sheet.MergedCells() {
+		refs = append(refs, mc.Reference())
+	}
+
+	return refs
+}
+
+func TestPlaceholderBlocks(t *testing.T) {
+	tmpl := createContractTemplate(t)
+
+	c := contract{
+		ContractNo:     "HT001",
+		HasAttachments: true,
+		Attachments:    []attachment{{Name: "报价单", Pages: 2}, {Name: "技术协议", Pages: 5}},
+		Note:           "原件",
+		Signer:         "张三",
+	}
+
+	sheet, data := writeContract(t, tmpl, c)
+	assert.Equal(t, "合同：HT001", sheet.Cell("A1").GetString())
+	assert.Equal(t, "附件清单", sheet.Cell("A2").GetString())
+	assert.Equal(t, "技术协议", sheet.Cell("A4").GetString())
+	assert.Equal(t, "附件说明：原件", sheet.Cell("A5").GetString())
+	assert.Equal(t, "签字：张三", sheet.Cell("A6").GetString())
+	assert.Equal(t, 6, len(sheet.Rows()))
+	assert.ElementsMatch(t, []string{"A2:C2", "A6:C6"}, mergedRefs(sheet))
+	assert.Equal(t, 40.0, *sheet.Row(6).X().HtAttr)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(data))
+	defer x.Close()
+
+	var read contract
+
+	assert.Nil(t, x.Read(&read))
+	assert.Equal(t, c, read)
+
+	c = contract{ContractNo: "HT002", Signer: "李四"}
+
+	sheet, data = writeContract(t, tmpl, c)
+	assert.Equal(t, "合同：HT002", sheet.Cell("A1").GetString())
+	assert.Equal(t, "无附件", sheet.Cell("A2").GetString())
+	assert.Equal(t, "签字：李四", sheet.Cell("A3").GetString())
+	assert.Equal(t, 3, len(sheet.Rows()))
+	assert.Equal(t, []string{"A3:C3"}, mergedRefs(sheet))
+	assert.Equal(t, 40.0, *sheet.Row(3).X().HtAttr)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(data))
+	defer x2.Close()
+
+	var read2 contract
+
+	assert.Nil(t, x2.Read(&read2))
+	assert.Equal(t, c, read2)
+}
+
+func TestPlaceholderBlockReferences(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("合同")
+	sheet.Cell("A1").SetString("{{if .HasAttachments}}附件清单")
+	sheet.Cell("A2").SetString("附件说明：{{Note}}{{end}}")
+	sheet.Cell("A3").SetString("签字：{{Signer}}")
+	sheet.Cell("B3").SetNumber(5)
+	sheet.Cell("C3").SetFormulaRaw("B3*2")
+	sheet.Cell("C4").SetFormulaRaw("SUM(B1:B3)")
+	sheet.Cell("D4").SetFormulaRaw("LEN(A2)")
+	sheet.AddDataValidation().SetRange("B2:B3")
+	wb.AddDefinedName("_xlnm.Print_Area", "'合同'!$A$1:$D$4")
+
+	tmpl := saveWorkbook(t, wb)
+
+	sheet, _ = writeContract(t, tmpl, contract{Signer: "李四"})
+	assert.Equal(t, "签字：李四", sheet.Cell("A1").GetString())
+	assert.Equal(t, "B1*2", sheet.Cell("C1").GetFormula())
+	assert.Equal(t, "SUM(B1:B1)", sheet.Cell("C2").GetFormula())
+	assert.Equal(t, "LEN(#REF!)", sheet.Cell("D2").GetFormula())
+	assert.Equal(t, sml.ST_Sqref{"B1:B1"}, sheet.X().DataValidations.DataValidation[0].SqrefAttr)
+}
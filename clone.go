@@ -0,0 +1,352 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/unidoc/unioffice/schema/soo/dml/spreadsheetDrawing"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// writePlaceholderBean writes the bean into the placeholders of the current sheet.
+func (x *Xlsx) writePlaceholderBean(fields []reflect.StructField, bean reflect.Value) (map[string]string, error) {
+	x.writePlaceholderBlocks(fields, bean)
+	vars := placeholderVars(fields, bean)
+
+	if err := x.writePlaceholderLoops(fields, bean, vars); err != nil {
+		return nil, err
+	}
+
+	if err := x.writePlaceholder(fields, x.collectPlaceholders(x.currentSheet), bean); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// writePlaceholderSheets writes the beans into the clones of the placeholder template sheet, one sheet per bean.
+// The first bean is written into the template sheet itself, and the others are written into the clones
+// appended to the workbook. The sheets are named by the docName tag like `docName:"{{Name}}登记表"`,
+// or the template sheet name with the sequence like Sheet1 (2).
+func (x *Xlsx) writePlaceholderSheets(r *run) error {
+	tmplSheet := x.currentSheet
+	tmplName := tmplSheet.Name()
+	sheets := []spreadsheet.Sheet{tmplSheet}
+
+	// the clones are made before writing, so all of them are cloned from the untouched template.
+	for i := 1; i < r.beanValue.Len(); i++ {
+		clone, err := x.cloneSheet(tmplSheet, fmt.Sprintf("%s~%d", tmplName, i))
+		if err != nil {
+			return err
+		}
+
+		sheets = append(sheets, clone)
+	}
+
+	for i, sheet := range sheets {
+		if err := x.ctxErr(); err != nil {
+			return err
+		}
+
+		bean := r.elem(i)
+		x.currentSheet, x.tmplSheet = sheet, sheet
+		vars, err := x.writePlaceholderBean(r.fields, bean)
+		if err != nil {
+			return err
+		}
+
+		x.writeSheetPlaceholderTexts(sheet, vars, false, false)
+		x.renameSheet(sheet, x.uniqueSheetName(x.docName(r, bean, tmplName), sheet))
+	}
+
+	return nil
+}
+
+// renameSheet renames the sheet, and the references to the sheet in the defined names like the print area.
+func (x *Xlsx) renameSheet(sheet spreadsheet.Sheet, name string) {
+	old := sheet.Name()
+	if old == name {
+		return
+	}
+
+	sheet.SetName(name)
+
+	re := regexp.MustCompile(`(^|[^\w'.])` + regexp.QuoteMeta(old) + `!`)
+
+	for _, dn := range x.workbook.DefinedNames() {
+		content := strings.ReplaceAll(dn.Content(), quoteSheetName(old)+"!", quoteSheetName(name)+"!")
+		content = re.ReplaceAllString(content, "${1}"+quoteSheetName(name)+"!")
+		dn.SetContent(content)
+	}
+}
+
+// docName interpolates the docName tag expression like {{Name}}登记表 by the bean,
+// and returns the fallback when there is no docName tag.
+func (x *Xlsx) docName(r *run, bean reflect.Value, fallback string) string {
+	expr := r.FindTtag("docName")
+	if expr == "" {
+		expr = fallback
+	}
+
+	pl := x.parsePlaceholder(expr)
+
+	return pl.Interpolate(placeholderVars(r.fields, bean))
+}
+
+// uniqueSheetName makes the valid sheet name which is not used by the other sheets,
+// by appending the sequence like (2) when the name is used.
+func (x *Xlsx) uniqueSheetName(name string, self spreadsheet.Sheet) string {
+	used := make(map[string]bool)
+
+	for _, sheet := range x.workbook.Sheets() {
+		if sheet.X() != self.X() {
+			used[strings.ToLower(sheet.Name())] = true
+		}
+	}
+
+	return uniqueName(SheetName(name), func(s string) bool { return used[strings.ToLower(s)] }, SheetName)
+}
+
+// uniqueName appends the sequence like (2) to the name until it is not used, fix fixes the appended name.
+func uniqueName(name string, used func(string) bool, fix func(string) string) string {
+	unique := name
+
+	for i := 2; used(unique); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		runes := []rune(fix(name + suffix))
+
+		if prefix := len(runes) - len([]rune(suffix)); prefix >= 0 {
+			unique = string(runes[:prefix]) + suffix
+		}
+	}
+
+	return unique
+}
+
+// cloneSheet clones the sheet with the values, styles, merged cells, validations, print setup,
+// the print area defined names and the drawing like the images and text boxes.
+// The spreadsheet.Workbook.CopySheet shares the rows with the source sheet and breaks the relationships
+// of the workbooks read from files, so the worksheet is deeply copied into a new added sheet.
+// The parts referred by the relationships of the sheet other than the drawing, like the comments,
+// the external hyperlinks and the tables, are not cloned.
+func (x *Xlsx) cloneSheet(src spreadsheet.Sheet, name string) (spreadsheet.Sheet, error) {
+	ws := sml.NewWorksheet()
+	if err := deepCopyXML(src.X(), ws); err != nil {
+		return spreadsheet.Sheet{}, fmt.Errorf("failed to clone sheet %s: %w", src.Name(), err)
+	}
+
+	removeSheetRelationships(ws)
+
+	clone := x.workbook.AddSheet()
+	clone.SetName(name)
+	*clone.X() = *ws
+
+	if err := x.cloneDrawing(src, clone); err != nil {
+		return spreadsheet.Sheet{}, err
+	}
+
+	x.cloneDefinedNames(src, clone)
+
+	return clone, nil
+}
+
+// removeSheetRelationships removes the references to the relationships of the sheet.
+func removeSheetRelationships(ws *sml.Worksheet) {
+	ws.Drawing, ws.LegacyDrawing, ws.LegacyDrawingHF, ws.DrawingHF = nil, nil, nil, nil
+	ws.Picture, ws.OleObjects, ws.Controls, ws.TableParts = nil, nil, nil, nil
+
+	if ws.PageSetup != nil {
+		ws.PageSetup.IdAttr = nil
+	}
+
+	if ws.Hyperlinks != nil {
+		links := ws.Hyperlinks.Hyperlink[:0]
+
+		for _, link := range ws.Hyperlinks.Hyperlink {
+			if link.IdAttr == nil {
+				links = append(links, link)
+			}
+		}
+
+		ws.Hyperlinks.Hyperlink = links
+
+		if len(links) == 0 {
+			ws.Hyperlinks = nil
+		}
+	}
+}
+
+func sheetIndex(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet) int {
+	for i, s := range wb.Sheets() {
+		if s.X() == sheet.X() {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// deepCopyXML copies the xml element from src to dst by marshaling and unmarshaling.
+func deepCopyXML(src, dst interface{}) error {
+	bs, err := xml.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal(bs, dst)
+}
+
+// cloneDrawing clones the drawing of the src sheet into a new drawing of the dst sheet,
+// with the relationships of the images and charts kept in the same ids.
+func (x *Xlsx) cloneDrawing(src, dst spreadsheet.Sheet) error {
+	srcDr, srcRels := src.GetDrawing()
+	if srcDr == nil {
+		return nil
+	}
+
+	d := x.workbook.AddDrawing()
+	dst.SetDrawing(d)
+
+	wsDr := spreadsheetDrawing.NewWsDr()
+	if err := deepCopyXML(srcDr, wsDr); err != nil {
+		return fmt.Errorf("failed to clone drawing of sheet %s: %w", src.Name(), err)
+	}
+
+	*d.X() = *wsDr
+
+	_, rels := dst.GetDrawing()
+
+	for _, rel := range srcRels.Relationships() {
+		rels.AddRelationship(rel.Target(), rel.Type()).SetID(rel.ID())
+	}
+
+	return nil
+}
+
+// cloneDefinedNames clones the defined names local to the src sheet like _xlnm.Print_Area.
+func (x *Xlsx) cloneDefinedNames(src, clone spreadsheet.Sheet) {
+	srcIdx, cloneIdx := sheetIndex(x.workbook, src), sheetIndex(x.workbook, clone)
+	re := regexp.MustCompile(`(^|[^\w'.])` + regexp.QuoteMeta(src.Name()) + `!`)
+	cloneRef := quoteSheetName(clone.Name()) + "!"
+
+	for _, dn := range x.workbook.DefinedNames() {
+		if id := dn.X().LocalSheetIdAttr; id == nil || int(*id) != srcIdx {
+			continue
+		}
+
+		content := strings.ReplaceAll(dn.Content(), quoteSheetName(src.Name())+"!", cloneRef)
+		content = re.ReplaceAllString(content, "${1}"+cloneRef)
+		x.workbook.AddDefinedName(dn.Name(), content).SetLocalSheetID(uint32(cloneIdx))
+	}
+}
+
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// ErrWriteZipAfterWrite defines the error of calling WriteZip after Write,
+// when the template is already filled and can not be used for the zipped workbooks.
+var ErrWriteZipAfterWrite = fmt.Errorf("WriteZip after Write")
+
+// WriteZip writes the beans in the placeholder mode into the separate workbooks by the template,
+// one workbook per bean, and zips them into w. The workbooks are named by the docName tag
+// like `docName:"{{Name}}登记表"` with the .xlsx extension, or by the sequence like 1.xlsx.
+func (x *Xlsx) WriteZip(w io.Writer, beans interface{}, writeOptionFns ...WriteOptionFn) error {
+	return x.WriteZipContext(context.Background(), w, beans, writeOptionFns...)
+}
+
+// WriteZipContext writes the beans into the zipped workbooks like WriteZip,
+// and stops with the error of ctx when it is canceled between the workbooks or the rows.
+func (x *Xlsx) WriteZipContext(ctx context.Context, w io.Writer, beans interface{},
+	writeOptionFns ...WriteOptionFn,
+) error {
+	if !x.hasInput() {
+		return ErrNoExcelRead
+	}
+
+	if x.written {
+		return ErrWriteZipAfterWrite
+	}
+
+	var tmpl bytes.Buffer
+	if err := x.workbook.Save(&tmpl); err != nil {
+		return err
+	}
+
+	r := makeRun(beans, writeOptionFns)
+	if err := r.checkNilBeans(); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool)
+
+	n := 1
+	if r.isSlice {
+		n = r.beanValue.Len()
+	}
+
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		bean := r.beanValue
+		if r.isSlice {
+			bean = r.elem(i)
+		}
+
+		name := x.docName(r, bean, fmt.Sprintf("%d", i+1))
+		name = strings.NewReplacer("/", "_", `\`, "_").Replace(name)
+		name = uniqueName(name, func(s string) bool { return used[strings.ToLower(s)] }, func(s string) string { return s })
+		used[strings.ToLower(name)] = true
+
+		if err := x.writeZipEntry(ctx, zw, name+".xlsx", tmpl.Bytes(), bean.Interface(), writeOptionFns); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (x *Xlsx) writeZipEntry(ctx context.Context, zw *zip.Writer, name string, tmpl []byte, bean interface{},
+	writeOptionFns []WriteOptionFn,
+) error {
+	wb, err := parseExcel(tmpl, Limits{})
+	if err != nil {
+		return err
+	}
+
+	option := *x.option
+	option.TemplateWorkbook, option.Workbook = wb, nil
+
+	xb, err := New(func(o *Option) { *o = option })
+	if err != nil { // New closes the workbook on errors.
+		return err
+	}
+
+	defer func() {
+		if err := xb.Close(); err != nil {
+			x.logger().Warn("failed to close workbook", "name", name, "error", err)
+		}
+	}()
+
+	if err := xb.WriteContext(ctx, bean, writeOptionFns...); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	return xb.Save(f)
+}
@@ -0,0 +1,179 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/common"
+	"github.com/unidoc/unioffice/measurement"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type registerForm struct {
+	Name   string `asPlaceholder:"true" docName:"{{Name}}登记表"`
+	Mobile string
+}
+
+func createFormTemplate(t *testing.T) []byte {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("登记表")
+	sheet.Cell("A1").SetString("姓名：{{Name}}")
+	sheet.Cell("A2").SetString("手机：{{Mobile}}")
+	sheet.AddMergedCells("A1", "C1")
+	sheet.AddDataValidation().SetRange("D1:D5")
+	wb.AddDefinedName("_xlnm.Print_Area", "'登记表'!$A$1:$C$2").SetLocalSheetID(0)
+
+	logo, err := common.ImageFromBytes(createPNG(20, 10))
+	assert.Nil(t, err)
+
+	ref, err := wb.AddImage(logo)
+	assert.Nil(t, err)
+
+	d := wb.AddDrawing()
+	sheet.SetDrawing(d)
+	anchor := d.AddImage(ref, spreadsheet.AnchorTypeTwoCell)
+	anchor.MoveTo(4, 0)
+	anchor.SetWidth(20 * measurement.Pixel72)
+	addTextBox(wb, sheet, "联系人：{{Name}}")
+
+	return saveWorkbook(t, wb)
+}
+
+func TestPlaceholderSheetPerBean(t *testing.T) {
+	tmpl := createFormTemplate(t)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	forms := []registerForm{{Name: "张三", Mobile: "1"}, {Name: "李四", Mobile: "2"}, {Name: "张三", Mobile: "3"}}
+	assert.Nil(t, x.Write(forms))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, out.Sheets(), 3)
+	assert.Nil(t, out.Validate())
+
+	names := []string{"张三登记表", "李四登记表", "张三登记表 (2)"}
+	for i, sheet := range out.Sheets() {
+		assert.Equal(t, names[i], sheet.Name())
+		assert.Equal(t, "姓名："+forms[i].Name, sheet.Cell("A1").GetString())
+		assert.Equal(t, "手机："+forms[i].Mobile, sheet.Cell("A2").GetString())
+		assert.Len(t, sheet.MergedCells(), 1)
+		assert.Len(t, sheet.X().DataValidations.DataValidation, 1)
+
+		wsDr, _ := sheet.GetDrawing()
+		assert.Len(t, wsDr.EG_Anchor, 2)
+		assert.Equal(t, "联系人："+forms[i].Name, wsDr.EG_Anchor[1].TwoCellAnchor.Choice.Sp.TxBody.P[0].EG_TextRun[0].R.T)
+	}
+
+	printAreas := make(map[string]bool)
+	for _, dn := range out.DefinedNames() {
+		printAreas[dn.Content()] = true
+	}
+
+	assert.Equal(t, map[string]bool{
+		"'张三登记表'!$A$1:$C$2": true, "'李四登记表'!$A$1:$C$2": true, "'张三登记表 (2)'!$A$1:$C$2": true,
+	}, printAreas)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read registerForm
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, forms[0], read)
+}
+
+func TestPlaceholderWriteZip(t *testing.T) {
+	tmpl := createFormTemplate(t)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	forms := []registerForm{{Name: "张三", Mobile: "1"}, {Name: "李/四", Mobile: "2"}, {Name: "张三", Mobile: "3"}}
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.WriteZip(&buf, forms))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, zr.File, 3)
+
+	names := []string{"张三登记表.xlsx", "李_四登记表.xlsx", "张三登记表 (2).xlsx"}
+	for i, f := range zr.File {
+		assert.Equal(t, names[i], f.Name)
+
+		rc, err := f.Open()
+		assert.Nil(t, err)
+
+		var data bytes.Buffer
+		_, _ = data.ReadFrom(rc)
+		_ = rc.Close()
+
+		x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(data.Bytes()))
+
+		var read registerForm
+
+		assert.Nil(t, x2.Read(&read))
+		assert.Equal(t, forms[i], read)
+		_ = x2.Close()
+	}
+}
+
+func TestPlaceholderWriteZipAfterWrite(t *testing.T) {
+	x, _ := xlsx.New(xlsx.WithTemplate(createFormTemplate(t)))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(registerForm{Name: "张三", Mobile: "1"}))
+
+	var buf bytes.Buffer
+
+	err := x.WriteZip(&buf, []registerForm{{Name: "李四", Mobile: "2"}})
+	assert.True(t, errors.Is(err, xlsx.ErrWriteZipAfterWrite))
+}
+
+func TestPlaceholderNilBeans(t *testing.T) {
+	forms := []*registerForm{{Name: "张三", Mobile: "1"}, nil}
+
+	x, _ := xlsx.New(xlsx.WithTemplate(createFormTemplate(t)))
+	defer x.Close()
+
+	err := x.Write(forms)
+	assert.True(t, errors.Is(err, xlsx.ErrNilBean))
+	assert.Contains(t, err.Error(), "index 1")
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(createFormTemplate(t)))
+	defer x2.Close()
+
+	var buf bytes.Buffer
+
+	err = x2.WriteZip(&buf, forms)
+	assert.True(t, errors.Is(err, xlsx.ErrNilBean))
+	assert.Contains(t, err.Error(), "index 1")
+}
+
+func TestPlaceholderPointerBeans(t *testing.T) {
+	forms := []*registerForm{{Name: "张三", Mobile: "1"}}
+
+	x, _ := xlsx.New(xlsx.WithTemplate(createFormTemplate(t)))
+	defer x.Close()
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.WriteZip(&buf, forms))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Len(t, zr.File, 1)
+	assert.Equal(t, "张三登记表.xlsx", zr.File[0].Name)
+}
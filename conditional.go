@@ -0,0 +1,374 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unioffice/color"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// ConditionalFormatType defines the type of conditional formatting rule.
+type ConditionalFormatType int
+
+const (
+	// CellValueRule highlights the cells by comparing the cell value, like less than 60.
+	CellValueRule ConditionalFormatType = iota
+	// FormulaRule highlights the cells when the formula evaluates to true.
+	FormulaRule
+	// ColorScaleRule shades the cells with 2 or 3 color gradients.
+	ColorScaleRule
+	// DataBarRule draws data bars in the cells.
+	DataBarRule
+	// IconSetRule shows icons in the cells.
+	IconSetRule
+	// DuplicateRule highlights the duplicate values.
+	DuplicateRule
+)
+
+// ConditionalFormat defines a conditional formatting rule applied to a column.
+type ConditionalFormat struct {
+	Type ConditionalFormatType
+	// Operator is the comparison operator for CellValueRule,
+	// any of lt, le, gt, ge, eq, ne, between and notBetween.
+	Operator string
+	// Values are the comparison values for CellValueRule, 2 values for between and notBetween,
+	// the texts are quoted, and the formulas like =$C$1 are used without the leading =.
+	Values []string
+	// Formula is the formula for FormulaRule, {row} will be replaced with the first written row number,
+	// like $B{row}<$C{row}.
+	Formula string
+	// Colors are hex colors, the fill color for CellValueRule, FormulaRule and DuplicateRule,
+	// the gradient colors for ColorScaleRule and the bar color for DataBarRule.
+	Colors []string
+	// FontColor is the hex font color for CellValueRule, FormulaRule and DuplicateRule.
+	FontColor string
+	// IconSet is the icon set name for IconSetRule, like 3Arrows, 3TrafficLights1, 5Rating.
+	IconSet string
+}
+
+// WithConditionalFormats defines the named conditional formatting rules
+// which can be referenced by the conditionalFormat tag.
+func WithConditionalFormats(v map[string][]ConditionalFormat) OptionFn {
+	return func(o *Option) { o.ConditionalFormats = v }
+}
+
+// ErrBadConditionalFormat defines the error of the bad conditionalFormat tag.
+var ErrBadConditionalFormat = fmt.Errorf("bad conditional format")
+
+// ParseConditionalFormats parses the inline conditionalFormat tag value.
+// The rules are separated by ; and each rule is like:
+// lt:60:FFC7CE, between:60,80:FFEB9C, formula:$B{row}<$C{row}:FFC7CE,
+// dataBar:638EC6, colorScale:F8696B,FFEB84,63BE7B, iconSet:3Arrows or duplicate:FFC7CE.
+func ParseConditionalFormats(tag string) ([]ConditionalFormat, error) {
+	rules := make([]ConditionalFormat, 0)
+
+	for _, s := range strings.Split(tag, ";") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+
+		rule, err := parseConditionalFormat(s)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func parseConditionalFormat(s string) (ConditionalFormat, error) {
+	name, args := s, ""
+	if p := strings.Index(s, ":"); p >= 0 {
+		name, args = s[:p], s[p+1:]
+	}
+
+	splitColors := func(v string) []string {
+		if v == "" {
+			return nil
+		}
+
+		return strings.Split(v, ",")
+	}
+
+	switch name {
+	case "dataBar":
+		return ConditionalFormat{Type: DataBarRule, Colors: splitColors(args)}, nil
+	case "colorScale":
+		return ConditionalFormat{Type: ColorScaleRule, Colors: splitColors(args)}, nil
+	case "iconSet":
+		return ConditionalFormat{Type: IconSetRule, IconSet: args}, nil
+	case "duplicate":
+		return ConditionalFormat{Type: DuplicateRule, Colors: splitColors(args)}, nil
+	case "formula":
+		// only the trailing hex colors are split off, the ranges like $B$2:$B$10 stay in the formula.
+		p := strings.LastIndex(args, ":")
+		if p < 0 || !isHexColors(args[p+1:]) {
+			return ConditionalFormat{Type: FormulaRule, Formula: args}, nil
+		}
+
+		return ConditionalFormat{Type: FormulaRule, Formula: args[:p], Colors: splitColors(args[p+1:])}, nil
+	}
+
+	if _, ok := parseCfOperator(name); !ok {
+		return ConditionalFormat{}, fmt.Errorf("unknown rule %s: %w", s, ErrBadConditionalFormat)
+	}
+
+	values, colors := args, ""
+	if p := strings.Index(args, ":"); p >= 0 {
+		values, colors = args[:p], args[p+1:]
+	}
+
+	if values == "" {
+		return ConditionalFormat{}, fmt.Errorf("no values in rule %s: %w", s, ErrBadConditionalFormat)
+	}
+
+	return ConditionalFormat{
+		Type:     CellValueRule,
+		Operator: name,
+		Values:   strings.Split(values, ","),
+		Colors:   splitColors(colors),
+	}, nil
+}
+
+var hexColorRe = regexp.MustCompile(`^[0-9A-Fa-f]{6}([0-9A-Fa-f]{2})?$`)
+
+// isHexColors tells whether the s is the comma separated hex colors, like FFC7CE or F8696B,63BE7B.
+func isHexColors(s string) bool {
+	for _, c := range strings.Split(s, ",") {
+		if !hexColorRe.MatchString(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cfValueFormula returns the formula of the CellValueRule value, the numbers and the formulas like =$C$1
+// are kept, and the texts are quoted.
+func cfValueFormula(v string) string {
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v
+	}
+
+	if strings.HasPrefix(v, "=") {
+		return v[1:]
+	}
+
+	if strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) && len(v) > 1 {
+		return v
+	}
+
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
+
+func parseCfOperator(s string) (sml.ST_ConditionalFormattingOperator, bool) {
+	switch s {
+	case "lt":
+		return sml.ST_ConditionalFormattingOperatorLessThan, true
+	case "le":
+		return sml.ST_ConditionalFormattingOperatorLessThanOrEqual, true
+	case "gt":
+		return sml.ST_ConditionalFormattingOperatorGreaterThan, true
+	case "ge":
+		return sml.ST_ConditionalFormattingOperatorGreaterThanOrEqual, true
+	case "eq":
+		return sml.ST_ConditionalFormattingOperatorEqual, true
+	case "ne":
+		return sml.ST_ConditionalFormattingOperatorNotEqual, true
+	case "between":
+		return sml.ST_ConditionalFormattingOperatorBetween, true
+	case "notBetween":
+		return sml.ST_ConditionalFormattingOperatorNotBetween, true
+	default:
+		return sml.ST_ConditionalFormattingOperatorUnset, false
+	}
+}
+
+// dataRowRanges returns the ranges of the column over the data rows written from firstRow,
+// the subtotal rows between and the totals row after are excluded, so they do not skew the scales.
+func (r *run) dataRowRanges(firstRow uint32, column string) []string {
+	if !r.isSlice {
+		return []string{fmt.Sprintf("%s%d", column, firstRow)}
+	}
+
+	refs := make([]string, 0)
+	start := -1
+
+	for i := 0; i <= len(r.rowBeans); i++ {
+		if i < len(r.rowBeans) && r.rowBeans[i] >= 0 {
+			if start < 0 {
+				start = i
+			}
+
+			continue
+		}
+
+		if start >= 0 {
+			from, to := firstRow+uint32(start), firstRow+uint32(i-1)
+			refs = append(refs, fmt.Sprintf("%s%d:%s%d", column, from, column, to))
+			start = -1
+		}
+	}
+
+	return refs
+}
+
+// createColumnConditionalFormats creates the conditional formats of the tag over the ranges of the column,
+// the {row} in the formulas is replaced by the first data row firstRow.
+func (x *Xlsx) createColumnConditionalFormats(sheet spreadsheet.Sheet, tag string, refs []string, firstRow uint32) error {
+	if tag == "" || len(refs) == 0 {
+		return nil
+	}
+
+	rules, ok := x.option.ConditionalFormats[tag]
+	if !ok {
+		var err error
+		if rules, err = ParseConditionalFormats(tag); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range rules {
+		if err := x.addConditionalFormat(sheet, refs, firstRow, rule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const (
+	defaultCfFillColor    = "FFC7CE"
+	defaultCfFontColor    = "9C0006"
+	defaultDataBarColor   = "638EC6"
+	defaultIconSet        = "3Arrows"
+	iconSetDefaultPercent = 3
+)
+
+// nolint:gomnd
+func (x *Xlsx) addConditionalFormat(sheet spreadsheet.Sheet, refs []string,
+	startRowNum uint32, rule ConditionalFormat,
+) error {
+	cf := sheet.AddConditionalFormatting(refs)
+	r := cf.AddRule()
+	r.SetPriority(x.nextCfPriority(sheet))
+
+	switch rule.Type {
+	case CellValueRule:
+		op, ok := parseCfOperator(rule.Operator)
+		if !ok || len(rule.Values) == 0 {
+			return fmt.Errorf("bad cell value rule %+v: %w", rule, ErrBadConditionalFormat)
+		}
+
+		r.SetType(sml.ST_CfTypeCellIs)
+		r.SetOperator(op)
+		formulas := make([]string, len(rule.Values))
+		for i, v := range rule.Values {
+			formulas[i] = cfValueFormula(v)
+		}
+
+		r.X().Formula = formulas
+		r.SetStyle(x.createDifferentialStyle(rule))
+	case FormulaRule:
+		r.SetType(sml.ST_CfTypeExpression)
+		r.X().Formula = []string{strings.ReplaceAll(rule.Formula, "{row}", strconv.Itoa(int(startRowNum)))}
+		r.SetStyle(x.createDifferentialStyle(rule))
+	case DuplicateRule:
+		r.SetType(sml.ST_CfTypeDuplicateValues)
+		r.SetStyle(x.createDifferentialStyle(rule))
+	case ColorScaleRule:
+		colors := rule.Colors
+		if len(colors) < 2 {
+			colors = []string{"F8696B", "FFEB84", "63BE7B"}
+		}
+
+		r.SetType(sml.ST_CfTypeColorScale)
+		cs := r.SetColorScale()
+		cs.AddFormatValue(sml.ST_CfvoTypeMin, "0")
+
+		if len(colors) > 2 {
+			cs.AddFormatValue(sml.ST_CfvoTypePercentile, "50")
+		}
+
+		cs.AddFormatValue(sml.ST_CfvoTypeMax, "0")
+
+		for _, c := range colors {
+			cs.AddGradientStop(color.FromHex(c))
+		}
+	case DataBarRule:
+		c := defaultDataBarColor
+		if len(rule.Colors) > 0 {
+			c = rule.Colors[0]
+		}
+
+		r.SetType(sml.ST_CfTypeDataBar)
+		db := r.SetDataBar()
+		db.AddFormatValue(sml.ST_CfvoTypeMin, "0")
+		db.AddFormatValue(sml.ST_CfvoTypeMax, "0")
+		db.SetColor(color.FromHex(c))
+	case IconSetRule:
+		name := rule.IconSet
+		if name == "" {
+			name = defaultIconSet
+		}
+
+		var t sml.ST_IconSetType
+		if err := t.UnmarshalXMLAttr(xml.Attr{Value: name}); err != nil || t == sml.ST_IconSetTypeUnset {
+			return fmt.Errorf("bad icon set %s: %w", name, ErrBadConditionalFormat)
+		}
+
+		r.SetType(sml.ST_CfTypeIconSet)
+		is := r.SetIcons()
+		is.SetIcons(t)
+
+		n := iconSetDefaultPercent
+		if v, err := strconv.Atoi(name[:1]); err == nil {
+			n = v
+		}
+
+		for i := 0; i < n; i++ {
+			is.AddFormatValue(sml.ST_CfvoTypePercent, strconv.Itoa(i*100/n))
+		}
+	default:
+		return fmt.Errorf("unknown rule type %d: %w", rule.Type, ErrBadConditionalFormat)
+	}
+
+	return nil
+}
+
+func (x *Xlsx) nextCfPriority(sheet spreadsheet.Sheet) int32 {
+	n := int32(0)
+
+	for _, cf := range sheet.X().ConditionalFormatting {
+		n += int32(len(cf.CfRule))
+	}
+
+	return n
+}
+
+func (x *Xlsx) createDifferentialStyle(rule ConditionalFormat) spreadsheet.DifferentialStyle {
+	fill, font := defaultCfFillColor, rule.FontColor
+	if len(rule.Colors) > 0 {
+		fill = rule.Colors[0]
+	} else if font == "" {
+		font = defaultCfFontColor
+	}
+
+	dxf := x.workbook.StyleSheet.AddDifferentialStyle()
+	pf := dxf.Fill().SetPatternFill()
+	pf.SetPattern(sml.ST_PatternTypeSolid)
+	pf.SetBgColor(color.FromHex(fill))
+
+	if font != "" {
+		dxf.X().Font = &sml.CT_Font{Color: []*sml.CT_Color{{RgbAttr: color.FromHex(font).AsRGBAString()}}}
+	}
+
+	return dxf
+}
@@ -0,0 +1,168 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestParseConditionalFormats(t *testing.T) {
+	rules, err := xlsx.ParseConditionalFormats("lt:60:FFC7CE;between:60,80;formula:$B{row}<$C{row}:FFEB9C;dataBar;iconSet:3Flags")
+	assert.Nil(t, err)
+	assert.Equal(t, []xlsx.ConditionalFormat{
+		{Type: xlsx.CellValueRule, Operator: "lt", Values: []string{"60"}, Colors: []string{"FFC7CE"}},
+		{Type: xlsx.CellValueRule, Operator: "between", Values: []string{"60", "80"}},
+		{Type: xlsx.FormulaRule, Formula: "$B{row}<$C{row}", Colors: []string{"FFEB9C"}},
+		{Type: xlsx.DataBarRule},
+		{Type: xlsx.IconSetRule, IconSet: "3Flags"},
+	}, rules)
+
+	rules, err = xlsx.ParseConditionalFormats("formula:$B{row}>AVERAGE($B$2:$B$10);formula:$B{row}>AVERAGE($B$2:$B$10):FFEB9C")
+	assert.Nil(t, err)
+	assert.Equal(t, []xlsx.ConditionalFormat{
+		{Type: xlsx.FormulaRule, Formula: "$B{row}>AVERAGE($B$2:$B$10)"},
+		{Type: xlsx.FormulaRule, Formula: "$B{row}>AVERAGE($B$2:$B$10)", Colors: []string{"FFEB9C"}},
+	}, rules)
+
+	_, err = xlsx.ParseConditionalFormats("bad:1")
+	assert.True(t, errors.Is(err, xlsx.ErrBadConditionalFormat))
+}
+
+type kpi struct {
+	Name   string  `title:"姓名" conditionalFormat:"duplicate"`
+	Score  float64 `title:"得分" conditionalFormat:"lt:60:FFC7CE;dataBar"`
+	Target float64 `title:"目标" conditionalFormat:"belowTarget"`
+}
+
+func TestConditionalFormat(t *testing.T) {
+	x, _ := xlsx.New(xlsx.WithConditionalFormats(map[string][]xlsx.ConditionalFormat{
+		"belowTarget": {
+			{Type: xlsx.FormulaRule, Formula: "$B{row}<$C{row}", Colors: []string{"FFEB9C"}},
+			{Type: xlsx.ColorScaleRule},
+		},
+	}))
+	defer x.Close()
+
+	err := x.Write([]kpi{
+		{Name: "a", Score: 50, Target: 60},
+		{Name: "b", Score: 90, Target: 80},
+		{Name: "a", Score: 70, Target: 60},
+	})
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	cfs := wb.Sheets()[0].X().ConditionalFormatting
+	assert.Equal(t, 5, len(cfs))
+
+	assert.Equal(t, sml.ST_Sqref{"A2:A4"}, *cfs[0].SqrefAttr)
+	assert.Equal(t, sml.ST_CfTypeDuplicateValues, cfs[0].CfRule[0].TypeAttr)
+	assert.Equal(t, sml.ST_CfTypeCellIs, cfs[1].CfRule[0].TypeAttr)
+	assert.Equal(t, []string{"60"}, cfs[1].CfRule[0].Formula)
+	assert.Equal(t, sml.ST_CfTypeDataBar, cfs[2].CfRule[0].TypeAttr)
+	assert.Equal(t, []string{"$B2<$C2"}, cfs[3].CfRule[0].Formula)
+	assert.Equal(t, sml.ST_CfTypeColorScale, cfs[4].CfRule[0].TypeAttr)
+	assert.Equal(t, int32(5), cfs[4].CfRule[0].PriorityAttr)
+}
+
+func TestConditionalFormatTemplate(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("考核结果")
+	sheet.Cell("A3").SetString("姓名")
+	sheet.Cell("B3").SetString("得分")
+	sheet.Cell("C3").SetString("目标")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithConditionalFormats(map[string][]xlsx.ConditionalFormat{
+		"belowTarget": {{Type: xlsx.FormulaRule, Formula: "$B{row}<$C{row}"}},
+	}))
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]kpi{{Name: "a", Score: 50, Target: 60}, {Name: "b", Score: 90, Target: 80}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	cfs := out.Sheets()[0].X().ConditionalFormatting
+	assert.Equal(t, 4, len(cfs))
+	assert.Equal(t, sml.ST_Sqref{"A4:A5"}, *cfs[0].SqrefAttr)
+	assert.Equal(t, sml.ST_Sqref{"B4:B5"}, *cfs[1].SqrefAttr)
+	assert.Equal(t, sml.ST_Sqref{"C4:C5"}, *cfs[3].SqrefAttr)
+	assert.Equal(t, []string{"$B4<$C4"}, cfs[3].CfRule[0].Formula)
+}
+
+func TestConditionalFormatDataRows(t *testing.T) {
+	type regionScore struct {
+		Region string  `title:"区域" conditionalFormat:"duplicate"`
+		Score  float64 `title:"得分" total:"sum" conditionalFormat:"dataBar;colorScale"`
+	}
+
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]regionScore{
+		{Region: "华东", Score: 50},
+		{Region: "华东", Score: 90},
+		{Region: "华北", Score: 70},
+	}, xlsx.WithSubtotals(xlsx.Subtotals{GroupBy: []string{"Region"}}), xlsx.WithTotalsRow("合计"))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := out.Sheets()[0]
+	assert.Equal(t, "华东 小计", sheet.Cell("A4").GetString())
+	assert.Equal(t, "华北 小计", sheet.Cell("A6").GetString())
+	assert.Equal(t, "合计", sheet.Cell("A7").GetString())
+
+	cfs := sheet.X().ConditionalFormatting
+	assert.Equal(t, 3, len(cfs))
+
+	for i, col := range []string{"A", "B", "B"} {
+		assert.Equal(t, sml.ST_Sqref{col + "2:" + col + "3", col + "5:" + col + "5"}, *cfs[i].SqrefAttr)
+	}
+}
+
+func TestConditionalFormatTextValues(t *testing.T) {
+	type task struct {
+		Name   string `title:"任务"`
+		Status string `title:"状态" conditionalFormat:"eq:完成:C6EFCE;ne:=$C$1"`
+	}
+
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]task{{Name: "a", Status: "完成"}, {Name: "b", Status: "进行中"}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	cfs := wb.Sheets()[0].X().ConditionalFormatting
+	assert.Equal(t, 2, len(cfs))
+	assert.Equal(t, []string{`"完成"`}, cfs[0].CfRule[0].Formula)
+	assert.Equal(t, []string{"$C$1"}, cfs[1].CfRule[0].Formula)
+}
@@ -0,0 +1,412 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bingoohuang/xlsx/pkg/cast"
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// WithColumns defines the column order of the map keys,
+// for writing []map[string]interface{} and the map fields tagged with `xlsx:"dynamic"`.
+// The keys not in the columns are appended in the sorted order.
+func WithColumns(columns ...string) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.Columns = columns
+	}
+}
+
+// isDynamicField tells whether the field is a map[string]T tagged with `xlsx:"dynamic"`,
+// which expands into extra columns after the static ones.
+func isDynamicField(f reflect.StructField) bool {
+	return f.Tag.Get("xlsx") == "dynamic" &&
+		f.Type.Kind() == reflect.Map && f.Type.Key().Kind() == reflect.String
+}
+
+// isMapSlice tells whether the beans is a slice (or a pointer of slice) of map[string]T.
+func isMapSlice(beans interface{}) bool {
+	t := reflect.TypeOf(beans)
+	if t == nil {
+		return false
+	}
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Map && t.Elem().Key().Kind() == reflect.String
+}
+
+// mapKeys collects the keys of the maps, ordered by the columns first and then sorted.
+func mapKeys(maps []reflect.Value, columns []string) []string {
+	keys := make([]string, 0, len(columns))
+	seen := make(map[string]bool)
+
+	for _, c := range columns {
+		if !seen[c] {
+			seen[c] = true
+			keys = append(keys, c)
+		}
+	}
+
+	others := make([]string, 0)
+
+	for _, m := range maps {
+		if !m.IsValid() || m.IsNil() {
+			continue
+		}
+
+		for _, k := range m.MapKeys() {
+			if key := k.String(); !seen[key] {
+				seen[key] = true
+				others = append(others, key)
+			}
+		}
+	}
+
+	sort.Strings(others)
+
+	return append(keys, others...)
+}
+
+// mapValue returns the value of the key in the map, nil when absent.
+func mapValue(m reflect.Value, key string) interface{} {
+	if !m.IsValid() || m.IsNil() {
+		return nil
+	}
+
+	v := m.MapIndex(reflect.ValueOf(key).Convert(m.Type().Key()))
+	if !v.IsValid() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+// ErrUnsupportedMapOption defines the error of the option which is not supported when writing the map rows.
+var ErrUnsupportedMapOption = fmt.Errorf("unsupported option for map rows")
+
+// writeMaps writes the slice of map[string]T with the keys as the titles.
+func (x *Xlsx) writeMaps(beans interface{}, writeOptionFns []WriteOptionFn) error {
+	r := &run{rawValue: reflect.ValueOf(beans), isSlice: true}
+	r.beanValue = reflect.Indirect(r.rawValue)
+	r.beanType = r.beanValue.Type().Elem()
+
+	for _, fn := range writeOptionFns {
+		fn(&r.writeOption)
+	}
+
+	if err := x.checkMapOptions(r.writeOption); err != nil {
+		return err
+	}
+
+	if r.beanValue.Len() == 0 {
+		return nil
+	}
+
+	x.tmplSheet, x.currentSheet = x.createWriteSheet(x.workbook, r)
+
+	maps := make([]reflect.Value, r.beanValue.Len())
+	for i := range maps {
+		maps[i] = r.beanValue.Index(i)
+	}
+
+	keys := mapKeys(maps, r.writeOption.Columns)
+	titleRow := x.currentSheet.AddRow()
+
+	for _, key := range keys {
+		cell := titleRow.AddCell()
+		cell.SetString(key)
+		x.setCellStyle(cell, r.writeOption.TitleStyle)
+	}
+
+	x.rowsWritten = 0
+
+	for _, m := range maps {
+		if err := x.ctxErr(); err != nil {
+			return err
+		}
+
+		row := x.currentSheet.AddRow()
+		x.rowsWritten++
+
+		for _, key := range keys {
+			setCellInterface(row.AddCell(), "", mapValue(m, key))
+		}
+
+		x.rowWritten(row.RowNumber())
+	}
+
+	lastCol := reference.IndexToColumn(uint32(len(keys) - 1))
+	titledRowNum := titleRow.RowNumber()
+
+	if r.writeOption.AutoWidth {
+		for i := range keys {
+			x.fitColumnWidth(reference.IndexToColumn(uint32(i)), reflect.StructField{},
+				r.writeOption, titledRowNum, titledRowNum+x.rowsWritten)
+		}
+	}
+
+	x.setupSheet(writtenRange{
+		titledRowNum: titledRowNum,
+		lastRowNum:   titledRowNum + x.rowsWritten,
+		firstCol:     "A",
+		lastCol:      lastCol,
+	}, r.writeOption)
+
+	return nil
+}
+
+// checkMapOptions rejects the options which need the struct tags or the title row located in the template,
+// instead of ignoring them silently.
+func (x *Xlsx) checkMapOptions(option WriteOption) error {
+	var unsupported []string
+
+	if x.hasInput() {
+		unsupported = append(unsupported, "template")
+	}
+
+	if option.MergeColsMode != DoNotMerge {
+		unsupported = append(unsupported, "MergeColsMode")
+	}
+
+	if option.TotalsRow {
+		unsupported = append(unsupported, "TotalsRow")
+	}
+
+	if option.Subtotals != nil {
+		unsupported = append(unsupported, "Subtotals")
+	}
+
+	if option.OutlineLevelField != "" {
+		unsupported = append(unsupported, "OutlineLevelField")
+	}
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s: %w", strings.Join(unsupported, ", "), ErrUnsupportedMapOption)
+	}
+
+	return nil
+}
+
+// dynamicColumn is a column expanded from the dynamic map field.
+type dynamicColumn struct {
+	Column string
+	Key    string
+}
+
+// dynamicKeys collects the keys of the dynamic map field over the beans.
+func (r *run) dynamicKeys() []string {
+	maps := make([]reflect.Value, 0)
+
+	if r.isSlice {
+		for i := 0; i < r.beanValue.Len(); i++ {
+			maps = append(maps, r.elem(i).FieldByIndex(r.dynamicField.Index))
+		}
+	} else {
+		maps = append(maps, r.beanValue.FieldByIndex(r.dynamicField.Index))
+	}
+
+	return mapKeys(maps, r.writeOption.Columns)
+}
+
+// writeDynamicColumns writes the dynamic columns from the column index firstCol,
+// with the titles at titledRowNum (0 for no title row) and the data rows from dataRowNum to lastRowNum.
+func (x *Xlsx) writeDynamicColumns(r *run, firstCol, titledRowNum, dataRowNum, lastRowNum uint32) []dynamicColumn {
+	if r.dynamicField == nil {
+		return nil
+	}
+
+	keys := r.dynamicKeys()
+	columns := make([]dynamicColumn, len(keys))
+
+	for i, key := range keys {
+		columns[i] = dynamicColumn{Column: reference.IndexToColumn(firstCol + uint32(i)), Key: key}
+	}
+
+	if titledRowNum > 0 {
+		titleRow := x.currentSheet.Row(titledRowNum)
+
+		for _, c := range columns {
+			cell := titleRow.Cell(c.Column)
+			cell.SetString(c.Key)
+			x.setCellStyle(cell, r.writeOption.TitleStyle)
+		}
+	}
+
+	x.writeDynamicValues(r, columns, dataRowNum, lastRowNum)
+
+	return columns
+}
+
+// writeTemplateDynamicColumns writes the dynamic columns into the titled template,
+// the columns are located by the title texts, or appended after the last title.
+func (x *Xlsx) writeTemplateDynamicColumns(r *run, l templateLocation) []dynamicColumn {
+	if r.dynamicField == nil {
+		return nil
+	}
+
+	titleRow := x.currentSheet.Row(l.titledRowNum)
+	existing := make(map[string]string)
+	lastIdx := uint32(0)
+
+	var lastTitleCell spreadsheet.Cell
+
+	for _, cell := range x.rowCells(titleRow) {
+		col, err := cell.Column()
+		if err != nil {
+			continue
+		}
+
+		if s := GetCellString(cell); s != "" {
+			existing[s] = col
+		}
+
+		if idx := reference.ColumnToIndex(col); idx >= lastIdx {
+			lastIdx = idx
+			lastTitleCell = cell
+		}
+	}
+
+	columns := make([]dynamicColumn, 0)
+
+	for _, key := range r.dynamicKeys() {
+		if col, ok := existing[key]; ok {
+			columns = append(columns, dynamicColumn{Column: col, Key: key})
+			continue
+		}
+
+		lastIdx++
+		col := reference.IndexToColumn(lastIdx)
+		cell := titleRow.Cell(col)
+		cell.SetString(key)
+
+		if lastTitleCell.X() != nil {
+			CopyCellStyle(lastTitleCell, cell)
+		}
+
+		columns = append(columns, dynamicColumn{Column: col, Key: key})
+	}
+
+	x.writeDynamicValues(r, columns, l.titledRowNum+1, l.titledRowNum+x.rowsWritten)
+
+	return columns
+}
+
+func (x *Xlsx) writeDynamicValues(r *run, columns []dynamicColumn, dataRowNum, lastRowNum uint32) {
+	for rowNum := dataRowNum; rowNum <= lastRowNum; rowNum++ {
+		bean, ok := r.rowBean(int(rowNum - dataRowNum))
+		if !ok {
+			continue
+		}
+
+		m := bean.FieldByIndex(r.dynamicField.Index)
+		row := x.currentSheet.Row(rowNum)
+
+		for _, c := range columns {
+			cell := row.Cell(c.Column)
+			setCellInterface(cell, r.dynamicField.Tag, mapValue(m, c.Key))
+			x.setFieldStyle(cell, *r.dynamicField)
+		}
+	}
+}
+
+func (x *Xlsx) fitDynamicColumnWidths(r *run, columns []dynamicColumn, startRow, endRow uint32) {
+	if startRow == 0 {
+		return
+	}
+
+	for _, c := range columns {
+		x.fitColumnWidth(c.Column, *r.dynamicField, r.writeOption, startRow, endRow)
+	}
+}
+
+// extendRange extends the last column of the written range to cover the dynamic columns.
+func extendRange(w writtenRange, columns []dynamicColumn) writtenRange {
+	for _, c := range columns {
+		if reference.ColumnToIndex(c.Column) > reference.ColumnToIndex(w.lastCol) {
+			w.lastCol = c.Column
+		}
+	}
+
+	return w
+}
+
+// locateDynamicColumns locates the columns in the title row which are not the static titled ones.
+func (x *Xlsx) locateDynamicColumns(l *templateLocation, field *reflect.StructField) {
+	if field == nil || !l.isValid() {
+		return
+	}
+
+	sheet := x.tmplSheet
+	if !sheet.IsValid() {
+		sheet = x.currentSheet
+	}
+
+	static := make(map[string]bool)
+	for _, tf := range l.titleFields {
+		static[tf.Column] = true
+	}
+
+	l.dynamicField = field
+
+	for _, cell := range x.rowCells(sheet.Row(l.titledRowNum)) {
+		col, err := cell.Column()
+		if err != nil || static[col] {
+			continue
+		}
+
+		if s := GetCellString(cell); s != "" {
+			l.dynamicColumns = append(l.dynamicColumns, dynamicColumn{Column: col, Key: s})
+		}
+	}
+}
+
+// readDynamicValues reads the dynamic columns of the row into a new map of the dynamic field type,
+// the number of the empty cells is returned too.
+func (x *Xlsx) readDynamicValues(l templateLocation, row spreadsheet.Row) (reflect.Value, int, error) {
+	mapType := l.dynamicField.Type
+	m := reflect.MakeMapWithSize(mapType, len(l.dynamicColumns))
+	emptyCells := 0
+
+	for _, c := range l.dynamicColumns {
+		cell := row.Cell(c.Column)
+		s := x.getCellString(cell)
+		if s == "" {
+			emptyCells++
+			continue
+		}
+
+		v, err := castMapValue(s, mapType.Elem(), l.dynamicField.Tag)
+		if err != nil {
+			x.cellFailed(cell.Reference(), err)
+			return reflect.Value{}, 0, err
+		}
+
+		m.SetMapIndex(reflect.ValueOf(c.Key).Convert(mapType.Key()), v)
+	}
+
+	return m, emptyCells, nil
+}
+
+func castMapValue(s string, t reflect.Type, tag reflect.StructTag) (reflect.Value, error) {
+	switch {
+	case t.Kind() == reflect.Interface:
+		return reflect.ValueOf(s), nil
+	case t == timeType:
+		tv, err := parseTime(tag, s)
+
+		return reflect.ValueOf(tv), err
+	default:
+		v, err := cast.ToAny(s, t)
+		if err != nil && tag.Get("omiterr") == "true" {
+			return reflect.Zero(t), nil
+		}
+
+		return v, err
+	}
+}
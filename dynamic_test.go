@@ -0,0 +1,108 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestWriteMaps(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]map[string]interface{}{
+		{"姓名": "张三", "年龄": 18, "城市": "北京"},
+		{"姓名": "李四", "年龄": 20, "备注": "新人", "标签": []string{"a", "b"}},
+	}, xlsx.WithColumns("姓名", "年龄"))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	assert.Equal(t, "姓名", sheet.Cell("A1").GetString())
+	assert.Equal(t, "年龄", sheet.Cell("B1").GetString())
+	assert.Equal(t, "城市", sheet.Cell("C1").GetString())
+	assert.Equal(t, "备注", sheet.Cell("D1").GetString())
+	assert.Equal(t, "18", sheet.Cell("B2").GetFormattedValue())
+	assert.Equal(t, "北京", sheet.Cell("C2").GetString())
+	assert.Equal(t, "", sheet.Cell("C3").GetString())
+	assert.Equal(t, "新人", sheet.Cell("D3").GetString())
+	assert.Equal(t, "[a b]", sheet.Cell("E3").GetString())
+}
+
+func TestWriteMapsUnsupportedOptions(t *testing.T) {
+	rows := []map[string]interface{}{{"姓名": "张三", "年龄": 18}}
+
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write(rows, xlsx.WithTotalsRow("合计"), xlsx.WithMergeColsMode(xlsx.MergeCols))
+	assert.True(t, errors.Is(err, xlsx.ErrUnsupportedMapOption))
+	assert.Contains(t, err.Error(), "MergeColsMode, TotalsRow")
+
+	wb := spreadsheet.New()
+	wb.AddSheet()
+
+	tmpl := saveWorkbook(t, wb)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x2.Close()
+
+	assert.True(t, errors.Is(x2.Write(rows), xlsx.ErrUnsupportedMapOption))
+
+	// the named conditional formats are only referenced by the struct tags, which do not affect the maps.
+	x3, _ := xlsx.New(xlsx.WithConditionalFormats(map[string][]xlsx.ConditionalFormat{
+		"low": {{Type: xlsx.CellValueRule, Operator: "lt", Values: []string{"60"}}},
+	}))
+	defer x3.Close()
+
+	assert.Nil(t, x3.Write(rows))
+}
+
+type monthlySale struct {
+	Product string             `title:"产品"`
+	Months  map[string]float64 `xlsx:"dynamic"`
+}
+
+func TestDynamicColumns(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	sales := []monthlySale{
+		{Product: "A", Months: map[string]float64{"1月": 10, "2月": 20}},
+		{Product: "B", Months: map[string]float64{"1月": 30, "3月": 40}},
+	}
+
+	assert.Nil(t, x.Write(sales, xlsx.WithColumns("3月")))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	assert.Equal(t, "产品", sheet.Cell("A1").GetString())
+	assert.Equal(t, "3月", sheet.Cell("B1").GetString())
+	assert.Equal(t, "1月", sheet.Cell("C1").GetString())
+	assert.Equal(t, "2月", sheet.Cell("D1").GetString())
+	assert.Equal(t, "40", sheet.Cell("B3").GetFormattedValue())
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read []monthlySale
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, sales, read)
+}
@@ -0,0 +1,244 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+)
+
+// PlaceholderPipe is a filter with its args in the placeholder, like date "yyyy年MM月dd日".
+type PlaceholderPipe struct {
+	Name string
+	Args []string
+}
+
+// PlaceholderFilterFn converts the value with the args of the pipe.
+type PlaceholderFilterFn func(value string, args []string) (string, error)
+
+// PlaceholderFilter defines a filter used in the placeholder pipes like {{Amount | money}}.
+type PlaceholderFilter struct {
+	// Format formats the value when writing.
+	Format PlaceholderFilterFn
+	// Parse parses the formatted value back when reading, nil to keep the formatted value.
+	Parse PlaceholderFilterFn
+}
+
+// dateFilterName is the name of the built-in date filter, which reads the time fields by their format tags.
+const dateFilterName = "date"
+
+// builtinPlaceholderFilters are the built-in filters, which can be overridden by WithPlaceholderFilters.
+// nolint:gochecknoglobals
+var builtinPlaceholderFilters = map[string]PlaceholderFilter{
+	dateFilterName: {Format: formatDateFilter, Parse: parseDateFilter},
+	"money":        {Format: formatMoneyFilter, Parse: parseMoneyFilter},
+	"yesno":        {Format: formatYesNoFilter, Parse: parseYesNoFilter},
+	"default":      {Format: formatDefaultFilter, Parse: parseDefaultFilter},
+}
+
+// WithPlaceholderFilters defines the custom filters by name for the placeholder pipes,
+// the built-in filters date, money, yesno and default can be overridden.
+func WithPlaceholderFilters(v map[string]PlaceholderFilter) OptionFn {
+	return func(o *Option) { o.PlaceholderFilters = v }
+}
+
+// lookupPlaceholderFilter looks up the filter by name in the custom filters, then in the built-in filters.
+func lookupPlaceholderFilter(filters map[string]PlaceholderFilter, name string) (PlaceholderFilter, bool) {
+	if f, ok := filters[name]; ok {
+		return f, true
+	}
+
+	f, ok := builtinPlaceholderFilters[name]
+
+	return f, ok
+}
+
+// parsePlaceholderExpr parses the placeholder expression like Amount | money "¥" into the var and the pipes,
+// and the pattern hint like \d+ in Mobile:\d+, the pattern with | should be quoted like Code:"A|B".
+func parsePlaceholderExpr(expr string) (string, string, []PlaceholderPipe) {
+	segments := splitOutsideQuotes(expr, '|')
+	varName, pattern := strings.TrimSpace(segments[0]), ""
+
+	if i := strings.Index(varName, ":"); i > 0 {
+		varName, pattern = strings.TrimSpace(varName[:i]), unquote(strings.TrimSpace(varName[i+1:]))
+	}
+
+	var pipes []PlaceholderPipe
+
+	for _, seg := range segments[1:] {
+		tokens := splitOutsideQuotes(strings.TrimSpace(seg), ' ')
+		pipe := PlaceholderPipe{}
+
+		for _, t := range tokens {
+			if t == "" {
+				continue
+			}
+
+			if pipe.Name == "" {
+				pipe.Name = t
+			} else {
+				pipe.Args = append(pipe.Args, unquote(t))
+			}
+		}
+
+		if pipe.Name != "" {
+			pipes = append(pipes, pipe)
+		}
+	}
+
+	return varName, pattern, pipes
+}
+
+// splitOutsideQuotes splits s by sep which is not quoted by " or '.
+func splitOutsideQuotes(s string, sep rune) []string {
+	var (
+		parts []string
+		quote rune
+		start int
+	)
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+func argOr(args []string, i int, defaultValue string) string {
+	if i < len(args) {
+		return args[i]
+	}
+
+	return defaultValue
+}
+
+// formatDateFilter formats the time value by the java style layout, default yyyy-MM-dd.
+// The value is in the layout yyyy-MM-dd HH:mm:ss like the time fields without the format tag,
+// which parseDateFilter parses back into, other layouts are parsed by guess.
+func formatDateFilter(value string, args []string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	t, err := time.ParseInLocation(defaultTimeLayout, value, time.Local)
+	if err != nil {
+		if t, err = dateparse.ParseLocal(value); err != nil {
+			return value, err
+		}
+	}
+
+	return t.Format(ParseJavaTimeFormat(argOr(args, 0, "yyyy-MM-dd"))), nil
+}
+
+func parseDateFilter(value string, args []string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	t, err := time.ParseInLocation(ParseJavaTimeFormat(argOr(args, 0, "yyyy-MM-dd")), value, time.Local)
+	if err != nil {
+		return value, err
+	}
+
+	return t.Format(defaultTimeLayout), nil
+}
+
+// formatMoneyFilter formats the number with the thousands separators and 2 decimals,
+// with the optional currency symbol like money "¥".
+func formatMoneyFilter(value string, args []string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value, err
+	}
+
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+
+	s := strconv.FormatFloat(f, 'f', 2, 64) // nolint:gomnd
+	intPart, decimals := s[:len(s)-3], s[len(s)-3:]
+
+	var b strings.Builder
+
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteRune(',')
+		}
+
+		b.WriteRune(r)
+	}
+
+	return sign + argOr(args, 0, "") + b.String() + decimals, nil
+}
+
+func parseMoneyFilter(value string, args []string) (string, error) {
+	v := strings.ReplaceAll(value, ",", "")
+	sign := ""
+
+	if strings.HasPrefix(v, "-") {
+		sign, v = "-", v[1:]
+	}
+
+	v = strings.TrimPrefix(v, argOr(args, 0, ""))
+
+	if _, err := strconv.ParseFloat(v, 64); err != nil && v != "" {
+		return value, fmt.Errorf("bad money %s: %w", value, err)
+	}
+
+	return sign + v, nil
+}
+
+// formatYesNoFilter formats the boolean value to yes or no texts, default yes and no.
+func formatYesNoFilter(value string, args []string) (string, error) {
+	if ParseBool(value, false) {
+		return argOr(args, 0, "yes"), nil
+	}
+
+	return argOr(args, 1, "no"), nil
+}
+
+func parseYesNoFilter(value string, args []string) (string, error) {
+	return strconv.FormatBool(value == argOr(args, 0, "yes")), nil
+}
+
+// formatDefaultFilter formats the empty value to the default text.
+func formatDefaultFilter(value string, args []string) (string, error) {
+	if value == "" {
+		return argOr(args, 0, ""), nil
+	}
+
+	return value, nil
+}
+
+func parseDefaultFilter(value string, args []string) (string, error) {
+	if value == argOr(args, 0, "") {
+		return "", nil
+	}
+
+	return value, nil
+}
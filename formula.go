@@ -0,0 +1,291 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/formula"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// WithTotalsRow appends a totals row under the written data,
+// the columns with the tag like `total:"sum"` are aggregated by formulas,
+// and the label is written in the first column without the total tag.
+func WithTotalsRow(label string) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.TotalsRow = true
+		o.TotalsLabel = label
+	}
+}
+
+// WithTotalsStyle defines the style of the totals row.
+func WithTotalsStyle(v Style) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.TotalsStyle = v
+	}
+}
+
+// ExpandRowFormula expands the {row} placeholders in the formula with the row number,
+// the leading = is removed, like =B{row}-C{row} to B2-C2 for row 2.
+func ExpandRowFormula(formula string, rowNum uint32) string {
+	formula = strings.TrimPrefix(strings.TrimSpace(formula), "=")
+
+	return strings.ReplaceAll(formula, "{row}", strconv.Itoa(int(rowNum)))
+}
+
+// setRowCellValue sets the cell value in the row, or the formula when the field has the formula tag,
+// or the picture when the field is an image column.
+func (x *Xlsx) setRowCellValue(cell spreadsheet.Cell, field reflect.StructField,
+	value reflect.Value, rowNum uint32,
+) error {
+	if f := field.Tag.Get("formula"); f != "" {
+		cell.SetFormulaRaw(ExpandRowFormula(f, rowNum))
+		return nil
+	}
+
+	if isImageField(field) {
+		return x.setImageCell(cell, field, value.FieldByIndex(field.Index).Interface())
+	}
+
+	setCellValue(cell, field, value)
+
+	return nil
+}
+
+// ErrUnknownAggregate defines the error of the unknown aggregate in the total tag.
+var ErrUnknownAggregate = fmt.Errorf("unknown aggregate")
+
+// AggregateFunction returns the excel function name for the aggregate name,
+// like sum, average(avg), count, counta, max and min.
+func AggregateFunction(aggregate string) (string, error) {
+	switch strings.ToLower(aggregate) {
+	case "sum":
+		return "SUM", nil
+	case "average", "avg":
+		return "AVERAGE", nil
+	case "count":
+		return "COUNT", nil
+	case "counta":
+		return "COUNTA", nil
+	case "max":
+		return "MAX", nil
+	case "min":
+		return "MIN", nil
+	default:
+		return "", fmt.Errorf("%s: %w", aggregate, ErrUnknownAggregate)
+	}
+}
+
+func untitledFields(fields []reflect.StructField) []TitleField {
+	tfs := make([]TitleField, len(fields))
+
+	for i, f := range fields {
+		tfs[i] = TitleField{Column: reference.IndexToColumn(uint32(i)), StructField: f}
+	}
+
+	return tfs
+}
+
+// writeTotalsRow writes the totals row at rowNum which aggregates the rows from startRow to endRow.
+func (x *Xlsx) writeTotalsRow(tfs []TitleField, option WriteOption, startRow, endRow, rowNum uint32) error {
+	if !option.TotalsRow {
+		return nil
+	}
+
+	row := x.currentSheet.Row(rowNum)
+	labeled := option.TotalsLabel == ""
+
+	for _, tf := range tfs {
+		cell := row.Cell(tf.Column)
+
+		if aggregate := option.aggregateOf(tf.StructField); aggregate != "" {
+			f, err := option.aggregateFormula(aggregate, tf.Column, startRow, endRow)
+			if err != nil {
+				return err
+			}
+
+			cell.SetFormulaRaw(f)
+		} else if !labeled {
+			cell.SetString(option.TotalsLabel)
+			labeled = true
+		}
+
+		x.setFieldStyle(cell, tf.StructField)
+		x.setCellStyle(cell, option.TotalsStyle)
+	}
+
+	x.rowWritten(rowNum)
+
+	return nil
+}
+
+// FormulaMode defines how to read the formula cells.
+type FormulaMode int
+
+const (
+	// FormulaAuto reads the cached value of the formula cell, evaluates the formula when no cached value exists.
+	FormulaAuto FormulaMode = iota
+	// FormulaCachedOnly reads only the cached value of the formula cell.
+	FormulaCachedOnly
+	// FormulaEvaluate always evaluates the formula.
+	FormulaEvaluate
+	// FormulaText reads the formula text like =B2-C2.
+	FormulaText
+)
+
+// WithFormulaMode defines how to read the formula cells.
+func WithFormulaMode(v FormulaMode) ReadOptionFn {
+	return func(o *ReadOption) {
+		o.FormulaMode = v
+	}
+}
+
+// getCellString returns the string of the cell for reading, formula cells are handled by the FormulaMode.
+func (x *Xlsx) getCellString(c spreadsheet.Cell) string {
+	c = x.resolveMergedCell(c)
+
+	if !c.HasFormula() {
+		return GetCellString(c)
+	}
+
+	switch x.readOption.FormulaMode {
+	case FormulaCachedOnly:
+		return GetCellString(c)
+	case FormulaText:
+		return "=" + c.GetFormula()
+	case FormulaEvaluate:
+		return x.evaluateCell(c)
+	default:
+		if s := GetCellString(c); s != "" {
+			return s
+		}
+
+		return x.evaluateCell(c)
+	}
+}
+
+func (x *Xlsx) evaluateCell(c spreadsheet.Cell) string {
+	if v, ok := x.evaluateSubtotal(c.GetFormula()); ok {
+		return v
+	}
+
+	if x.evaluator == nil {
+		x.evaluator = formula.NewEvaluator()
+	}
+
+	result := x.evaluator.Eval(x.currentSheet.FormulaContext(), c.GetFormula())
+
+	switch result.Type {
+	case formula.ResultTypeNumber:
+		if result.IsBoolean {
+			return strconv.FormatBool(result.ValueNumber != 0)
+		}
+
+		return strconv.FormatFloat(result.ValueNumber, 'f', -1, 64)
+	case formula.ResultTypeString:
+		return strings.TrimSpace(result.ValueString)
+	case formula.ResultTypeError, formula.ResultTypeEmpty:
+		return ""
+	default:
+		return strings.TrimSpace(result.Value())
+	}
+}
+
+var subtotalPattern = regexp.MustCompile(`^(?i)SUBTOTAL\(\s*(\d+)\s*,\s*\$?([A-Z]+)\$?(\d+):\$?([A-Z]+)\$?(\d+)\s*\)$`)
+
+// evaluateSubtotal evaluates the SUBTOTAL formula over a range, which is not supported by the formula engine.
+// The cells with SUBTOTAL formulas in the range are ignored like Excel does.
+// nolint:gomnd
+func (x *Xlsx) evaluateSubtotal(f string) (string, bool) {
+	m := subtotalPattern.FindStringSubmatch(strings.TrimSpace(f))
+	if m == nil {
+		return "", false
+	}
+
+	num, _ := strconv.Atoi(m[1])
+	fromCol, toCol := reference.ColumnToIndex(m[2]), reference.ColumnToIndex(m[4])
+	fromRow, _ := strconv.Atoi(m[3])
+	toRow, _ := strconv.Atoi(m[5])
+
+	values := make([]float64, 0)
+	nonEmpty := 0
+
+	// the existing cells are scanned instead of sheet.Cell, which creates the missing ones while reading.
+	for _, row := range x.currentSheet.Rows() {
+		if n := row.RowNumber(); n < uint32(fromRow) || n > uint32(toRow) {
+			continue
+		}
+
+		for _, c := range x.rowCells(row) {
+			col, err := c.Column()
+			if err != nil {
+				continue
+			}
+
+			if idx := reference.ColumnToIndex(col); idx < fromCol || idx > toCol {
+				continue
+			}
+
+			if c.HasFormula() && subtotalPattern.MatchString(strings.TrimSpace(c.GetFormula())) {
+				continue
+			}
+
+			s := x.getCellString(c)
+			if s == "" {
+				continue
+			}
+
+			nonEmpty++
+
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				values = append(values, v)
+			}
+		}
+	}
+
+	result := 0.0
+
+	switch num % 100 {
+	case 1: // AVERAGE
+		if len(values) == 0 {
+			return "", true
+		}
+
+		result = sumFloats(values) / float64(len(values))
+	case 2: // COUNT
+		result = float64(len(values))
+	case 3: // COUNTA
+		result = float64(nonEmpty)
+	case 4, 5: // MAX, MIN
+		if len(values) == 0 {
+			return "0", true
+		}
+
+		result = values[0]
+
+		for _, v := range values[1:] {
+			if num%100 == 4 && v > result || num%100 == 5 && v < result {
+				result = v
+			}
+		}
+	case 9: // SUM
+		result = sumFloats(values)
+	default:
+		return "", false
+	}
+
+	return strconv.FormatFloat(result, 'f', -1, 64), true
+}
+
+func sumFloats(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum
+}
@@ -0,0 +1,162 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestExpandRowFormula(t *testing.T) {
+	assert.Equal(t, "B2-C2", xlsx.ExpandRowFormula("=B{row}-C{row}", 2))
+	assert.Equal(t, "SUM(A1:A3)", xlsx.ExpandRowFormula("SUM(A1:A3)", 5))
+}
+
+type profit struct {
+	Region  string  `title:"区域"`
+	Income  float64 `title:"收入" total:"sum"`
+	Expense float64 `title:"支出" total:"avg"`
+	Profit  float64 `title:"利润" formula:"=B{row}-C{row}" total:"sum"`
+}
+
+func TestFormulaAndTotals(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]profit{
+		{Region: "华东", Income: 100, Expense: 60},
+		{Region: "华北", Income: 200, Expense: 50},
+	}, xlsx.WithTotalsRow("合计"), xlsx.WithTotalsStyle(xlsx.Style{Bold: true}))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	assert.Equal(t, "B2-C2", sheet.Cell("D2").GetFormula())
+	assert.Equal(t, "B3-C3", sheet.Cell("D3").GetFormula())
+	assert.Equal(t, "合计", sheet.Cell("A4").GetString())
+	assert.Equal(t, "SUM(B2:B3)", sheet.Cell("B4").GetFormula())
+	assert.Equal(t, "AVERAGE(C2:C3)", sheet.Cell("C4").GetFormula())
+	assert.Equal(t, "SUM(D2:D3)", sheet.Cell("D4").GetFormula())
+}
+
+func TestFormulaAndTotalsTemplate(t *testing.T) {
+	type memberTotal struct {
+		Total     int `title:"会员总数" sheet:"会员" total:"sum"`
+		New       int `title:"其中：新增" total:"sum"`
+		Effective int `title:"其中：有效" formula:"A{row}-B{row}"`
+	}
+
+	x, _ := xlsx.New(xlsx.WithTemplate("testdata/template.xlsx"))
+	defer x.Close()
+
+	err := x.Write([]memberTotal{
+		{Total: 100, New: 50},
+		{Total: 200, New: 60},
+	}, xlsx.WithTotalsRow(""))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet, _ := wb.GetSheet("会员")
+	rows := sheet.Rows()
+	last := rows[len(rows)-1]
+	n := last.RowNumber()
+
+	assert.Equal(t, fmt.Sprintf("A%d-B%d", n-1, n-1), sheet.Row(n-1).Cell("C").GetFormula())
+	assert.Equal(t, fmt.Sprintf("SUM(A%d:A%d)", n-2, n-1), last.Cell("A").GetFormula())
+	assert.Equal(t, fmt.Sprintf("SUM(B%d:B%d)", n-2, n-1), last.Cell("B").GetFormula())
+	assert.Equal(t, "", last.Cell("C").GetFormula())
+}
+
+func TestReadFormula(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]profit{
+		{Region: "华东", Income: 100, Expense: 60},
+		{Region: "华北", Income: 200, Expense: 50},
+	}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var profits []profit
+
+	assert.Nil(t, x2.Read(&profits))
+	assert.Equal(t, []profit{
+		{Region: "华东", Income: 100, Expense: 60, Profit: 40},
+		{Region: "华北", Income: 200, Expense: 50, Profit: 150},
+	}, profits)
+
+	assert.Nil(t, x2.Read(&profits, xlsx.WithFormulaMode(xlsx.FormulaCachedOnly)))
+	assert.Equal(t, 0.0, profits[0].Profit)
+
+	type profitText struct {
+		Profit string `title:"利润"`
+	}
+
+	var texts []profitText
+
+	assert.Nil(t, x2.Read(&texts, xlsx.WithFormulaMode(xlsx.FormulaText)))
+	assert.Equal(t, []profitText{{Profit: "=B2-C2"}, {Profit: "=B3-C3"}}, texts)
+}
+
+func TestReadSubtotalFormula(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("区域")
+	sheet.Cell("B1").SetString("金额")
+	sheet.Cell("A2").SetString("华东")
+	sheet.Cell("B2").SetNumber(100)
+	sheet.Cell("A4").SetString("华北")
+	sheet.Cell("B4").SetNumber(200)
+	sheet.Cell("A5").SetString("合计")
+	sheet.Cell("B5").SetFormulaRaw("SUBTOTAL(9,B2:C4)")
+
+	x, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	type regionAmount struct {
+		Region string  `title:"区域"`
+		Amount float64 `title:"金额"`
+	}
+
+	var amounts []regionAmount
+
+	assert.Nil(t, x.Read(&amounts))
+	assert.Equal(t, []regionAmount{{"华东", 100}, {"华北", 200}, {"合计", 300}}, amounts)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	// reading the formula does not create the missing cells of the range.
+	rows := make([]uint32, 0)
+	for _, row := range out.Sheets()[0].Rows() {
+		rows = append(rows, row.RowNumber())
+	}
+
+	assert.Equal(t, []uint32{1, 2, 4, 5}, rows)
+	assert.Equal(t, 2, len(out.Sheets()[0].Row(2).Cells()))
+}
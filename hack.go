@@ -3,11 +3,11 @@ package xlsx
 import (
 	"flag"
 	"fmt"
+	"log/slog"
 	"strconv"
 	"strings"
 	"unsafe"
 
-	"github.com/unidoc/unioffice"
 	"github.com/unidoc/unioffice/schema/soo/sml"
 	"github.com/unidoc/unioffice/spreadsheet"
 	"github.com/unidoc/unioffice/spreadsheet/reference"
@@ -16,19 +16,27 @@ import (
 // RowCells returns a slice of cells.  The cells can be manipulated, but appending
 // to the slice will have no effect.
 func RowCells(r spreadsheet.Row) []spreadsheet.Cell {
+	return rowCells(slog.Default(), r)
+}
+
+func (x *Xlsx) rowCells(r spreadsheet.Row) []spreadsheet.Cell {
+	return rowCells(x.logger(), r)
+}
+
+func rowCells(logger *slog.Logger, r spreadsheet.Row) []spreadsheet.Cell {
 	var ret []spreadsheet.Cell
 
 	lastIndex := -1
 
 	for _, c := range r.X().C {
 		if c.RAttr == nil {
-			unioffice.Log("RAttr is nil for a cell, skipping.")
+			logger.Warn("RAttr is nil for a cell, skipping", "row", r.RowNumber())
 			continue
 		}
 
 		ref, err := reference.ParseCellReference(*c.RAttr)
 		if err != nil {
-			unioffice.Log("RAttr is incorrect for a cell: " + *c.RAttr + ", skipping.")
+			logger.Warn("RAttr is incorrect for a cell, skipping", "cell", *c.RAttr, "error", err)
 			continue
 		}
 
@@ -0,0 +1,18 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// saveWorkbook saves the workbook created in the tests as the bytes for WithTemplate and WithExcel.
+func saveWorkbook(t *testing.T, wb *spreadsheet.Workbook) []byte {
+	var buf bytes.Buffer
+
+	assert.Nil(t, wb.Save(&buf))
+
+	return buf.Bytes()
+}
@@ -1,36 +1,45 @@
 package xlsx
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"mime"
 	"net/http"
 
 	"github.com/unidoc/unioffice/spreadsheet"
 )
 
-// WithUpload defines the input excel file for reading.
+// WithUpload defines the input excel file for reading, the error of opening the upload file is returned by New,
+// like ErrMissingFormField when the request has no file form field of the key.
 func WithUpload(r *http.Request, filenameKey string) OptionFn {
-	wb, err := parseUploadFile(r, filenameKey)
-	if err != nil {
-		log.Printf("W! failed to open template excel %v", err)
-		return nil
-	}
+	return withOpen(func(o *Option) error {
+		wb, err := parseUploadFile(r, filenameKey, o.Limits)
+		if err != nil {
+			return fmt.Errorf("failed to open upload excel: %w", err)
+		}
+
+		o.Workbook = wb
 
-	return func(o *Option) { o.Workbook = wb }
+		return nil
+	})
 }
 
 // nolint:gomnd
-func parseUploadFile(r *http.Request, filenameKey string) (*spreadsheet.Workbook, error) {
+func parseUploadFile(r *http.Request, filenameKey string, limits Limits) (*spreadsheet.Workbook, error) {
 	_ = r.ParseMultipartForm(32 << 20) // limit your max input length!
 
 	file, header, err := r.FormFile(filenameKey)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, fmt.Errorf("%w: %s", ErrMissingFormField, filenameKey)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
 	defer file.Close()
 
-	return spreadsheet.Read(file, header.Size)
+	return readExcel(file, header.Size, limits)
 }
 
 // Download downloads the excels file in the http response.
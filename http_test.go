@@ -1,7 +1,10 @@
 package xlsx_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -35,3 +38,19 @@ func TestUpload(t *testing.T) {
 
 	assert.Equal(t, "file.xlsx", fn)
 }
+
+func TestUploadMissingFormField(t *testing.T) {
+	var body bytes.Buffer
+
+	w := multipart.NewWriter(&body)
+	assert.Nil(t, w.WriteField("name", "value"))
+	assert.Nil(t, w.Close())
+
+	r := httptest.NewRequest("POST", "/upload", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+
+	x, err := xlsx.New(xlsx.WithUpload(r, "file"))
+	assert.Nil(t, x)
+	assert.True(t, errors.Is(err, xlsx.ErrMissingFormField))
+	assert.Contains(t, err.Error(), "file")
+}
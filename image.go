@@ -0,0 +1,285 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // register the gif decoder for image fields
+	_ "image/jpeg" // register the jpeg decoder for image fields
+	"image/png"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+
+	"github.com/unidoc/unioffice"
+	"github.com/unidoc/unioffice/common"
+	"github.com/unidoc/unioffice/measurement"
+	"github.com/unidoc/unioffice/schema/soo/dml"
+	"github.com/unidoc/unioffice/schema/soo/dml/spreadsheetDrawing"
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// DefaultImageHeight is the default height in points of the images written into the cells.
+const DefaultImageHeight = 60
+
+// ErrBadImageSize defines the error of the image without a valid size.
+var ErrBadImageSize = fmt.Errorf("bad image size")
+
+// nolint:gochecknoglobals
+var (
+	bytesType = reflect.TypeOf([]byte(nil))
+	imageType = reflect.TypeOf((*image.Image)(nil)).Elem()
+)
+
+// isImageType tells the type is []byte or image.Image.
+func isImageType(t reflect.Type) bool {
+	return t == bytesType || t == imageType
+}
+
+// isImageField tells the field is an image column, which is []byte or image.Image tagged with image:"true".
+func isImageField(f reflect.StructField) bool {
+	return isImageType(f.Type) && ParseBool(f.Tag.Get("image"), false)
+}
+
+// imageHeight returns the image height in points by the imageHeight tag, default DefaultImageHeight.
+func imageHeight(f reflect.StructField) float64 {
+	if h, err := strconv.ParseFloat(f.Tag.Get("imageHeight"), 64); err == nil && h > 0 {
+		return h
+	}
+
+	return DefaultImageHeight
+}
+
+// imageBytes returns the encoded image data of the []byte or image.Image value, image.Image is encoded as png.
+func imageBytes(v interface{}) ([]byte, error) {
+	switch iv := v.(type) {
+	case []byte:
+		return iv, nil
+	case image.Image:
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, iv); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// setImageCell writes the image field value as a picture anchored to the cell,
+// the row height is enlarged to the image height when it is lower.
+func (x *Xlsx) setImageCell(cell spreadsheet.Cell, f reflect.StructField, v interface{}) error {
+	data, err := imageBytes(v)
+	if err != nil {
+		x.cellFailed(cell.Reference(), err)
+		return fmt.Errorf("failed to encode image of %s: %w", f.Name, err)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := x.addCellImage(x.currentSheet, cell.Reference(), data, imageHeight(f)); err != nil {
+		x.cellFailed(cell.Reference(), err)
+		return fmt.Errorf("failed to add image of %s to %s: %w", f.Name, cell.Reference(), err)
+	}
+
+	return nil
+}
+
+// addCellImage adds the image anchored to the top left of the cell with the height in points,
+// the width is scaled by the image aspect ratio. The existing drawing of the sheet, like a logo in the template,
+// is kept and the image is appended into it.
+func (x *Xlsx) addCellImage(sheet spreadsheet.Sheet, cellRef string, data []byte, height float64) error {
+	ref, err := reference.ParseCellReference(cellRef)
+	if err != nil {
+		return err
+	}
+
+	img, err := common.ImageFromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	if img.Size.X <= 0 || img.Size.Y <= 0 {
+		return fmt.Errorf("%w %v", ErrBadImageSize, img.Size)
+	}
+
+	imgRef, err := x.workbook.AddImage(img)
+	if err != nil {
+		return err
+	}
+
+	wsDr, rels := sheet.GetDrawing()
+	if wsDr == nil {
+		sheet.SetDrawing(x.workbook.AddDrawing())
+		wsDr, rels = sheet.GetDrawing()
+	}
+
+	target := fmt.Sprintf("../media/image%d.%s", len(x.workbook.Images), imgRef.Format())
+	rel := rels.AddRelationship(target, unioffice.ImageType)
+
+	cy := int64(height * measurement.Point / measurement.EMU)
+	cx := cy * int64(img.Size.X) / int64(img.Size.Y)
+
+	anchor := spreadsheetDrawing.NewCT_OneCellAnchor()
+	anchor.From.Col = int32(ref.ColumnIdx)
+	anchor.From.Row = int32(ref.RowIdx - 1)
+	anchor.Ext.CxAttr, anchor.Ext.CyAttr = cx, cy
+	anchor.Choice = &spreadsheetDrawing.EG_ObjectChoicesChoice{Pic: newPicture(len(wsDr.EG_Anchor)+1, rel.ID(), cx, cy)}
+	wsDr.EG_Anchor = append(wsDr.EG_Anchor, &spreadsheetDrawing.EG_Anchor{OneCellAnchor: anchor})
+
+	row := sheet.Row(ref.RowIdx)
+	if ht := row.X().HtAttr; ht == nil || *ht < height {
+		row.SetHeight(measurement.Distance(height * measurement.Point))
+	}
+
+	return nil
+}
+
+func newPicture(id int, relID string, cx, cy int64) *spreadsheetDrawing.CT_Picture {
+	pic := spreadsheetDrawing.NewCT_Picture()
+	pic.NvPicPr.CNvPr.IdAttr = uint32(id)
+	pic.NvPicPr.CNvPr.NameAttr = fmt.Sprintf("Image %d", id)
+	pic.BlipFill.Blip = dml.NewCT_Blip()
+	pic.BlipFill.Blip.EmbedAttr = &relID
+	pic.BlipFill.Stretch = dml.NewCT_StretchInfoProperties()
+	pic.SpPr = dml.NewCT_ShapeProperties()
+	pic.SpPr.Xfrm = dml.NewCT_Transform2D()
+	pic.SpPr.Xfrm.Off = dml.NewCT_Point2D()
+	pic.SpPr.Xfrm.Off.XAttr.ST_CoordinateUnqualified = unioffice.Int64(0)
+	pic.SpPr.Xfrm.Off.YAttr.ST_CoordinateUnqualified = unioffice.Int64(0)
+	pic.SpPr.Xfrm.Ext = dml.NewCT_PositiveSize2D()
+	pic.SpPr.Xfrm.Ext.CxAttr, pic.SpPr.Xfrm.Ext.CyAttr = cx, cy
+	pic.SpPr.PrstGeom = dml.NewCT_PresetGeometry2D()
+	pic.SpPr.PrstGeom.PrstAttr = dml.ST_ShapeTypeRect
+
+	return pic
+}
+
+// cellImage returns the data of the image anchored to the cell of the current sheet,
+// the images are loaded from the sheet drawing at the first call.
+func (x *Xlsx) cellImage(cellRef string) []byte {
+	if x.cellImages == nil {
+		x.cellImages = loadCellImages(x.logger(), x.workbook, x.currentSheet)
+	}
+
+	return x.cellImages[cellRef]
+}
+
+// loadCellImages loads the pictures in the sheet drawing by the cell references of their top left anchors.
+func loadCellImages(logger *slog.Logger, wb *spreadsheet.Workbook, sheet spreadsheet.Sheet) map[string][]byte {
+	images := make(map[string][]byte)
+
+	wsDr, rels := sheet.GetDrawing()
+	if wsDr == nil {
+		return images
+	}
+
+	for _, anchor := range wsDr.EG_Anchor {
+		from, pic := anchorPicture(anchor)
+		if pic == nil || pic.BlipFill.Blip == nil || pic.BlipFill.Blip.EmbedAttr == nil {
+			continue
+		}
+
+		data := workbookImage(logger, wb, rels.GetTargetByRelId(*pic.BlipFill.Blip.EmbedAttr))
+		if data == nil {
+			continue
+		}
+
+		cellRef := reference.IndexToColumn(uint32(from.Col)) + strconv.Itoa(int(from.Row)+1)
+		if _, ok := images[cellRef]; !ok {
+			images[cellRef] = data
+		}
+	}
+
+	return images
+}
+
+// workbookImage returns the image data by the target like ../media/image1.png,
+// the images read from the file are extracted to the temporary files by unioffice.
+func workbookImage(logger *slog.Logger, wb *spreadsheet.Workbook, target string) []byte {
+	for _, img := range wb.Images {
+		if img.Target() != target {
+			continue
+		}
+
+		if d := img.Data(); d != nil {
+			return *d
+		}
+
+		data, err := os.ReadFile(img.Path())
+		if err != nil {
+			logger.Warn("failed to read image", "target", target, "error", err)
+			return nil
+		}
+
+		return data
+	}
+
+	return nil
+}
+
+func anchorPicture(anchor *spreadsheetDrawing.EG_Anchor) (*spreadsheetDrawing.CT_Marker, *spreadsheetDrawing.CT_Picture) {
+	switch {
+	case anchor.TwoCellAnchor != nil && anchor.TwoCellAnchor.Choice != nil:
+		return anchor.TwoCellAnchor.From, anchor.TwoCellAnchor.Choice.Pic
+	case anchor.OneCellAnchor != nil && anchor.OneCellAnchor.Choice != nil:
+		return anchor.OneCellAnchor.From, anchor.OneCellAnchor.Choice.Pic
+	default:
+		return nil, nil
+	}
+}
+
+// setImageField sets the image anchored to the cell into the image field,
+// and returns false when there is no image anchored to the cell.
+func (x *Xlsx) setImageField(bean reflect.Value, f reflect.StructField, cellRef string) (bool, error) {
+	data := x.cellImage(cellRef)
+	if data == nil {
+		return false, nil
+	}
+
+	if f.Type == bytesType {
+		bean.FieldByIndex(f.Index).SetBytes(data)
+		return true, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("failed to decode image of field %s at cell %s: %w", f.Name, cellRef, err)
+	}
+
+	bean.FieldByIndex(f.Index).Set(reflect.ValueOf(&img).Elem())
+
+	return true, nil
+}
+
+// placeholderImage returns the value of the image field by the placeholder name.
+func placeholderImage(fields []reflect.StructField, v reflect.Value, name string) interface{} {
+	for _, f := range fields {
+		if placeholderName(f) == name {
+			return v.FieldByIndex(f.Index).Interface()
+		}
+	}
+
+	return nil
+}
+
+// readPlaceholderImage reads the image anchored to the cell of the whole cell placeholder of the image field.
+func (x *Xlsx) readPlaceholderImage(bean reflect.Value, f reflect.StructField, shifts loopRowShifts) error {
+	for k, pl := range x.collectPlaceholders(x.tmplSheet) {
+		if ref, err := reference.ParseCellReference(k); err == nil && shifts.removes(ref.RowIdx) {
+			continue
+		}
+
+		if name, ok := wholeCellVar(pl); ok && name == placeholderName(f) {
+			_, err := x.setImageField(bean, f, shifts.shiftRef(k))
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func createPNG(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w; i++ {
+		img.Set(i, i%h, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+
+	return buf.Bytes()
+}
+
+type employeePhoto struct {
+	Name   string      `title:"姓名"`
+	Photo  []byte      `title:"照片" image:"true"`
+	Avatar image.Image `title:"头像" image:"true" imageHeight:"30"`
+}
+
+func TestImageColumns(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	avatar := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	employees := []employeePhoto{
+		{Name: "张三", Photo: createPNG(40, 20), Avatar: avatar},
+		{Name: "李四", Photo: createPNG(20, 40)},
+	}
+
+	assert.Nil(t, x.Write(employees))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	wsDr, _ := sheet.GetDrawing()
+	assert.Len(t, wsDr.EG_Anchor, 3)
+	assert.Equal(t, float64(xlsx.DefaultImageHeight), *sheet.Row(2).X().HtAttr)
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read []employeePhoto
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Len(t, read, 2)
+	assert.Equal(t, employees[0].Photo, read[0].Photo)
+	assert.Equal(t, avatar.Bounds(), read[0].Avatar.Bounds())
+	assert.Equal(t, employees[1].Photo, read[1].Photo)
+	assert.Nil(t, read[1].Avatar)
+}
+
+type badge struct {
+	Name  string `asPlaceholder:"true"`
+	Photo []byte `image:"true"`
+}
+
+func TestImagePlaceholder(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("姓名：{{Name}}")
+	sheet.Cell("B2").SetString("{{Photo}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	b := badge{Name: "张三", Photo: createPNG(30, 40)}
+	assert.Nil(t, x.Write(b))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Equal(t, "", out.Sheets()[0].Cell("B2").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read badge
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, b, read)
+}
+
+func TestImageColumnError(t *testing.T) {
+	var failedCells []string
+
+	x, _ := xlsx.New(xlsx.WithHooks(xlsx.Hooks{
+		CellFailed: func(sheet, cellRef string, _ error) { failedCells = append(failedCells, cellRef) },
+	}))
+	defer x.Close()
+
+	err := x.Write([]employeePhoto{{Name: "张三", Photo: []byte("not an image")}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Photo")
+	assert.Equal(t, []string{"B2"}, failedCells)
+}
+
+func TestUntaggedBytesPlaceholder(t *testing.T) {
+	type note struct {
+		Name string `asPlaceholder:"true"`
+		Memo []byte
+	}
+
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("{{Name}}:{{Memo}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(note{Name: "张三", Memo: createPNG(3, 3)}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	drawing, _ := out.Sheets()[0].GetDrawing()
+	assert.Nil(t, drawing)
+}
@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// ErrLimitExceeded defines the error of the workbook read exceeding the limits, like a zip bomb upload.
+var ErrLimitExceeded = fmt.Errorf("limit exceeded")
+
+// Limits defines the safety limits of the workbooks read, the zero values are unlimited.
+// The uncompressed size and the shared strings are checked on opening the workbook,
+// and the rows, columns and cell lengths are checked on reading the sheet.
+type Limits struct {
+	// MaxRows is the max number of the rows in the sheet read.
+	MaxRows int
+	// MaxColumns is the max column number of the cells in the sheet read.
+	MaxColumns int
+	// MaxCellLength is the max number of the characters of a cell.
+	MaxCellLength int
+	// MaxUncompressedSize is the max total uncompressed size in bytes of the parts in the xlsx zip.
+	MaxUncompressedSize int64
+	// MaxSharedStrings is the max number of the shared strings of the workbook.
+	MaxSharedStrings int
+}
+
+// WithLimits defines the safety limits of the workbooks read, which protects from the zip bombs
+// uploaded by WithUpload, the workbooks of WithExcel, WithTemplate and WithUpload are all limited.
+func WithLimits(limits Limits) OptionFn {
+	return func(o *Option) { o.Limits = limits }
+}
+
+// checkZip checks the total uncompressed size of the zip before reading the workbook,
+// the zip reader fails on the parts larger than their declared sizes.
+// The broken zip is left to the workbook reading to report.
+func (l Limits) checkZip(r io.ReaderAt, size int64) error {
+	if l.MaxUncompressedSize <= 0 {
+		return nil
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil // nolint:nilerr
+	}
+
+	total := uint64(0)
+
+	for _, f := range zr.File {
+		if total += f.UncompressedSize64; total > uint64(l.MaxUncompressedSize) {
+			return fmt.Errorf("%w: uncompressed size is more than %d bytes", ErrLimitExceeded, l.MaxUncompressedSize)
+		}
+	}
+
+	return nil
+}
+
+func (l Limits) checkSharedStrings(wb *spreadsheet.Workbook) error {
+	if l.MaxSharedStrings <= 0 {
+		return nil
+	}
+
+	if n := len(wb.SharedStrings.X().Si); n > l.MaxSharedStrings {
+		return fmt.Errorf("%w: %d shared strings are more than %d", ErrLimitExceeded, n, l.MaxSharedStrings)
+	}
+
+	return nil
+}
+
+// checkSheet checks the rows, the columns and the cell lengths of the sheet to read.
+func (l Limits) checkSheet(sheet spreadsheet.Sheet, sharedStrings spreadsheet.SharedStrings) error {
+	if l.MaxRows <= 0 && l.MaxColumns <= 0 && l.MaxCellLength <= 0 {
+		return nil
+	}
+
+	rows := sheet.X().SheetData.Row
+	if l.MaxRows > 0 && len(rows) > l.MaxRows {
+		return fmt.Errorf("%w: %d rows of sheet %s are more than %d", ErrLimitExceeded, len(rows), sheet.Name(), l.MaxRows)
+	}
+
+	// the cells are checked by the raw references, the gaps are not filled like Row.Cells.
+	for _, row := range rows {
+		for _, c := range row.C {
+			if c.RAttr == nil {
+				continue
+			}
+
+			if err := l.checkCell(c, sharedStrings); err != nil {
+				return fmt.Errorf("%w at cell %s of sheet %s", err, *c.RAttr, sheet.Name())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l Limits) checkCell(c *sml.CT_Cell, sharedStrings spreadsheet.SharedStrings) error {
+	ref, err := reference.ParseCellReference(*c.RAttr)
+	if err != nil {
+		return nil // nolint:nilerr
+	}
+
+	if l.MaxColumns > 0 && int(ref.ColumnIdx) >= l.MaxColumns {
+		return fmt.Errorf("%w: column %s is more than %d columns", ErrLimitExceeded, ref.Column, l.MaxColumns)
+	}
+
+	if l.MaxCellLength > 0 {
+		if n := utf8.RuneCountInString(rawCellString(c, sharedStrings)); n > l.MaxCellLength {
+			return fmt.Errorf("%w: %d characters are more than %d", ErrLimitExceeded, n, l.MaxCellLength)
+		}
+	}
+
+	return nil
+}
+
+// rawCellString returns the string of the raw cell like Cell.GetString,
+// the shared strings are looked up by the index without finding the cell in the row.
+func rawCellString(c *sml.CT_Cell, sharedStrings spreadsheet.SharedStrings) string {
+	switch c.TAttr {
+	case sml.ST_CellTypeInlineStr:
+		if c.Is != nil && c.Is.T != nil {
+			return *c.Is.T
+		}
+	case sml.ST_CellTypeS:
+		if c.V == nil {
+			return ""
+		}
+
+		id, err := strconv.Atoi(*c.V)
+		if err != nil {
+			return ""
+		}
+
+		s, _ := sharedStrings.GetString(id)
+
+		return s
+	}
+
+	if c.V != nil {
+		return *c.V
+	}
+
+	return ""
+}
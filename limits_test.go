@@ -0,0 +1,146 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func createMemberStats(t *testing.T) []byte {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("会员")
+	sheet.Cell("A1").SetString("会员总数")
+	sheet.Cell("B1").SetString("其中：新增")
+	sheet.Cell("C1").SetString("其中：有效")
+
+	for _, row := range [][]float64{{100, 50, 50}, {200, 60, 140}} {
+		r := sheet.AddRow()
+		for _, v := range row {
+			r.AddCell().SetNumber(v)
+		}
+	}
+
+	return saveWorkbook(t, wb)
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := 0
+
+	x, _ := xlsx.New(xlsx.WithHooks(xlsx.Hooks{
+		RowWritten: func(string, uint32) {
+			if rows++; rows == 1 {
+				cancel()
+			}
+		},
+	}))
+	defer x.Close()
+
+	err := x.WriteContext(ctx, []memberStat{{Total: 100}, {Total: 200}, {Total: 300}})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, rows)
+
+	x2, _ := xlsx.New(xlsx.WithExcel(createMemberStats(t)))
+	defer x2.Close()
+
+	var stats []memberStat
+
+	assert.True(t, errors.Is(x2.ReadContext(ctx, &stats), context.Canceled))
+	assert.Nil(t, x2.ReadContext(context.Background(), &stats))
+	assert.Equal(t, []memberStat{{Total: 100, New: 50, Effective: 50}, {Total: 200, New: 60, Effective: 140}}, stats)
+}
+
+func TestLimits(t *testing.T) {
+	data := createMemberStats(t)
+
+	// the limits apply to the excel options before them too.
+	_, err := xlsx.New(xlsx.WithExcel(data), xlsx.WithLimits(xlsx.Limits{MaxUncompressedSize: 1024}))
+	assert.True(t, errors.Is(err, xlsx.ErrLimitExceeded), err)
+
+	_, err = xlsx.New(xlsx.WithLimits(xlsx.Limits{MaxSharedStrings: 2}), xlsx.WithExcel(data))
+	assert.True(t, errors.Is(err, xlsx.ErrLimitExceeded), err)
+
+	for _, c := range []struct {
+		limits xlsx.Limits
+		cell   string
+	}{
+		{limits: xlsx.Limits{MaxRows: 2}},
+		{limits: xlsx.Limits{MaxColumns: 2}, cell: "C1"},
+		{limits: xlsx.Limits{MaxCellLength: 4}, cell: "B1"},
+	} {
+		x, err := xlsx.New(xlsx.WithExcel(data), xlsx.WithLimits(c.limits))
+		assert.Nil(t, err)
+
+		var stats []memberStat
+
+		err = x.Read(&stats)
+		assert.True(t, errors.Is(err, xlsx.ErrLimitExceeded), err)
+		assert.Contains(t, err.Error(), c.cell)
+		assert.Nil(t, x.Close())
+	}
+
+	x, err := xlsx.New(xlsx.WithExcel(data), xlsx.WithLimits(xlsx.Limits{
+		MaxRows: 3, MaxColumns: 3, MaxCellLength: 5, MaxUncompressedSize: 1 << 20, MaxSharedStrings: 3,
+	}))
+	assert.Nil(t, err)
+
+	defer x.Close()
+
+	var stats []memberStat
+
+	assert.Nil(t, x.Read(&stats))
+	assert.Equal(t, 2, len(stats))
+}
+
+func TestContextCanceledPaths(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	x, _ := xlsx.New(xlsx.WithExcel(createMemberStats(t)), xlsx.WithLimits(xlsx.Limits{MaxColumns: 2}))
+	defer x.Close()
+
+	_, err := x.ReadTableContext(ctx)
+	assert.True(t, errors.Is(err, xlsx.ErrLimitExceeded), err)
+
+	x2, _ := xlsx.New(xlsx.WithExcel(createMemberStats(t)))
+	defer x2.Close()
+
+	_, err = x2.ReadTableContext(ctx)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	x3, _ := xlsx.New()
+	defer x3.Close()
+
+	err = x3.WriteContext(ctx, []map[string]interface{}{{"会员总数": 100}})
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	x4, _ := xlsx.New(xlsx.WithTemplate(createOrderTemplate(t)))
+	defer x4.Close()
+
+	err = x4.WriteContext(ctx, orderForm{Items: []formItem{{Name: "苹果", Qty: 3}}})
+	assert.True(t, errors.Is(err, context.Canceled), err)
+
+	x5, _ := xlsx.New(xlsx.WithTemplate(createOrderTemplate(t)))
+	defer x5.Close()
+
+	var buf bytes.Buffer
+
+	err = x5.WriteZipContext(ctx, &buf, []orderForm{{OrderNo: "D001"}, {OrderNo: "D002"}})
+	assert.True(t, errors.Is(err, context.Canceled), err)
+}
+
+func createOrderTemplate(t *testing.T) []byte {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("订单：{{OrderNo}}")
+	sheet.Cell("A2").SetString("{{range .Items}}{{.Name}}")
+	sheet.Cell("B2").SetString("{{.Qty}}{{end}}")
+
+	return saveWorkbook(t, wb)
+}
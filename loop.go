@@ -0,0 +1,614 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// placeholderLoop is a template row repeated for each element of a slice field,
+// which is marked by {{range .Items}} in any cell of the row, and the element fields are
+// referred by {{.Name}} in the row, the trailing {{end}} is optional.
+type placeholderLoop struct {
+	RowNum uint32
+	// Name is the placeholder name of the slice field.
+	Name string
+	// Cells are the template cells of the row with the range markers removed, ordered by columns.
+	Cells []loopCell
+	// Merges are the column index ranges of the merged cells inside the row.
+	Merges [][2]uint32
+	// Next are the template cells of the next row, which stops the repeated rows when reading.
+	Next []loopCell
+}
+
+type loopCell struct {
+	Column string
+	PlaceholderValue
+}
+
+// parseRangeVar parses the slice name from the placeholder var like "range .Items".
+func parseRangeVar(v string) (string, bool) {
+	fields := strings.Fields(v)
+	if len(fields) == 2 && fields[0] == "range" { // nolint:gomnd
+		return strings.TrimPrefix(fields[1], "."), true
+	}
+
+	return "", false
+}
+
+// removeLoopMarkers removes the {{range .Items}}, {{end}} and the block markers from the placeholder value.
+func removeLoopMarkers(pl PlaceholderValue) (PlaceholderValue, string) {
+	name := ""
+	parts := make([]PlaceholderPart, 0, len(pl.Parts))
+	content := ""
+
+	for _, p := range pl.Parts {
+		if v, ok := parseRangeVar(p.Var); ok {
+			name = v
+			continue
+		}
+
+		if _, _, ok := parseIfVar(p.Var); ok || p.Var == "end" {
+			continue
+		}
+
+		parts = append(parts, p)
+		content += p.Part
+	}
+
+	return PlaceholderValue{Content: content, Parts: parts, logger: pl.logger}, name
+}
+
+func (x *Xlsx) collectPlaceholderLoops(sheet spreadsheet.Sheet) []placeholderLoop {
+	loops := make([]placeholderLoop, 0)
+	rows := sheet.Rows()
+
+	for i, row := range rows {
+		loop := placeholderLoop{RowNum: row.RowNumber()}
+
+		for _, cell := range x.rowCells(row) {
+			col, err := cell.Column()
+			if err != nil {
+				continue
+			}
+
+			pl, name := removeLoopMarkers(x.parsePlaceholder(GetCellString(cell)))
+			if name != "" {
+				loop.Name = name
+			}
+
+			loop.Cells = append(loop.Cells, loopCell{Column: col, PlaceholderValue: pl})
+		}
+
+		if loop.Name == "" {
+			continue
+		}
+
+		if i+1 < len(rows) && rows[i+1].RowNumber() == loop.RowNum+1 {
+			for _, cell := range x.rowCells(rows[i+1]) {
+				col, err := cell.Column()
+				if s := GetCellString(cell); err == nil && s != "" {
+					loop.Next = append(loop.Next, loopCell{Column: col, PlaceholderValue: x.removeBlockMarkers(x.parsePlaceholder(s))})
+				}
+			}
+		}
+
+		for _, mc := range sheet.MergedCells() {
+			from, to, err := reference.ParseRangeReference(mc.Reference())
+			if err == nil && from.RowIdx == loop.RowNum && to.RowIdx == loop.RowNum {
+				loop.Merges = append(loop.Merges, [2]uint32{from.ColumnIdx, to.ColumnIdx})
+			}
+		}
+
+		loops = append(loops, loop)
+	}
+
+	return loops
+}
+
+func isLoopRow(loops []placeholderLoop, rowNum uint32) bool {
+	for _, l := range loops {
+		if l.RowNum == rowNum {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findPlaceholderField finds the slice field by the placeholder name.
+func findPlaceholderField(fields []reflect.StructField, name string) (reflect.StructField, bool) {
+	for _, f := range fields {
+		if placeholderName(f) == name && f.Type.Kind() == reflect.Slice {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func placeholderName(f reflect.StructField) string {
+	if name := f.Tag.Get("placeholder"); name != "" {
+		return name
+	}
+
+	return f.Name
+}
+
+// elemPlaceholderVars returns the vars of the loop element, like .Name for the field Name,
+// or . for the non-struct element, the outer vars are kept too.
+func elemPlaceholderVars(vars map[string]string, elem reflect.Value) map[string]string {
+	elemVars := make(map[string]string, len(vars))
+	for k, v := range vars {
+		elemVars[k] = v
+	}
+
+	elem = reflect.Indirect(elem)
+
+	if !elem.IsValid() {
+		return elemVars // the nil element leaves the element placeholders empty.
+	}
+
+	if elem.Kind() != reflect.Struct {
+		elemVars["."] = fmt.Sprintf("%v", elem.Interface())
+		return elemVars
+	}
+
+	addPlaceholderVars(elemVars, ".", exportedFields(elem.Type()), elem)
+
+	return elemVars
+}
+
+// loopFieldTypes collects the fields of the struct (or pointer to struct) elements of the items
+// by the var names like .Name.
+func loopFieldTypes(items reflect.Value) map[string]reflect.StructField {
+	fieldTypes := make(map[string]reflect.StructField)
+
+	if !items.IsValid() {
+		return fieldTypes
+	}
+
+	t := items.Type().Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Struct {
+		placeholderFieldTypes(fieldTypes, ".", exportedFields(t))
+	}
+
+	return fieldTypes
+}
+
+// writePlaceholderLoops expands the loop rows, the loops are expanded from the bottom,
+// so the row numbers of the loops above are not shifted.
+func (x *Xlsx) writePlaceholderLoops(fields []reflect.StructField, v reflect.Value, vars map[string]string) error {
+	loops := x.collectPlaceholderLoops(x.currentSheet)
+
+	for i := len(loops) - 1; i >= 0; i-- {
+		if err := x.expandPlaceholderLoop(loops[i], fields, v, vars); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *Xlsx) expandPlaceholderLoop(l placeholderLoop, fields []reflect.StructField,
+	v reflect.Value, vars map[string]string,
+) error {
+	var items reflect.Value
+
+	if f, ok := findPlaceholderField(fields, l.Name); ok {
+		items = v.FieldByIndex(f.Index)
+	} else {
+		x.logger().Warn("unable to find slice field for loop", "range", l.Name)
+	}
+
+	n := 0
+	if items.IsValid() {
+		n = items.Len()
+	}
+
+	if n > 1 {
+		shiftRowsDown(x.workbook, x.currentSheet, l.RowNum, uint32(n-1))
+	}
+
+	if n == 0 {
+		// no element, no row: the template row is removed and the rows below are shifted up.
+		removeRows(x.workbook, x.currentSheet, l.RowNum, 1)
+		return nil
+	}
+
+	tmplRow := x.currentSheet.Row(l.RowNum)
+	fieldTypes := loopFieldTypes(items)
+
+	defer sortRows(x.currentSheet)
+
+	for i := 0; i < n; i++ {
+		if err := x.ctxErr(); err != nil {
+			return err
+		}
+
+		row := tmplRow
+
+		if i > 0 {
+			row = x.currentSheet.Row(l.RowNum + uint32(i))
+			copyLoopRow(x.currentSheet, tmplRow, row, l)
+		}
+
+		elemVars := elemPlaceholderVars(vars, items.Index(i))
+
+		for _, c := range l.Cells {
+			x.setPlaceholderCell(row.Cell(c.Column), c.PlaceholderValue, elemVars, fieldTypes)
+		}
+
+		x.rowWritten(row.RowNumber())
+	}
+
+	return nil
+}
+
+// copyLoopRow copies the height, cell styles and merged cells of the loop template row.
+func copyLoopRow(sheet spreadsheet.Sheet, from, to spreadsheet.Row, l placeholderLoop) {
+	to.X().HtAttr = from.X().HtAttr
+	to.X().CustomHeightAttr = from.X().CustomHeightAttr
+
+	for _, c := range l.Cells {
+		CopyCellStyle(from.Cell(c.Column), to.Cell(c.Column))
+	}
+
+	for _, m := range l.Merges {
+		sheet.AddMergedCells(fmt.Sprintf("%s%d", reference.IndexToColumn(m[0]), to.RowNumber()),
+			fmt.Sprintf("%s%d", reference.IndexToColumn(m[1]), to.RowNumber()))
+	}
+}
+
+// shiftRowsDown shifts the rows after the row afterRow down by n rows, with the merged cells and the images,
+// and the references to the shifted rows like the formulas, the data validations and the defined names.
+func shiftRowsDown(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet, afterRow, n uint32) {
+	for _, row := range sheet.X().SheetData.Row {
+		if row.RAttr == nil || *row.RAttr <= afterRow {
+			continue
+		}
+
+		rowNum := *row.RAttr + n
+		row.RAttr = &rowNum
+
+		for _, c := range row.C {
+			if c.RAttr == nil {
+				continue
+			}
+
+			if ref, err := reference.ParseCellReference(*c.RAttr); err == nil {
+				r := fmt.Sprintf("%s%d", ref.Column, ref.RowIdx+n)
+				c.RAttr = &r
+			}
+		}
+	}
+
+	s := rowShift{afterRow: afterRow, n: int(n)}
+	shiftReferences(wb, sheet, s)
+	shiftDrawing(sheet, s)
+
+	mcs := sheet.X().MergeCells
+	if mcs == nil {
+		return
+	}
+
+	for _, mc := range mcs.MergeCell {
+		from, to, err := reference.ParseRangeReference(mc.RefAttr)
+		if err != nil || to.RowIdx <= afterRow {
+			continue
+		}
+
+		if from.RowIdx > afterRow {
+			from.RowIdx += n
+		}
+
+		to.RowIdx += n
+		mc.RefAttr = fmt.Sprintf("%s%d:%s%d", from.Column, from.RowIdx, to.Column, to.RowIdx)
+	}
+}
+
+func sortRows(sheet spreadsheet.Sheet) {
+	rows := sheet.X().SheetData.Row
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].RAttr != nil && rows[j].RAttr != nil && *rows[i].RAttr < *rows[j].RAttr
+	})
+}
+
+// loopRowShifts are the numbers of the rows inserted by the loops after the template rows,
+// or the negative numbers of the rows removed by the blocks ending at the template rows.
+type loopRowShifts []rowShift
+
+type rowShift struct {
+	afterRow uint32
+	n        int
+}
+
+// shift returns the row number in the written sheet of the template row number.
+func (s loopRowShifts) shift(rowNum uint32) uint32 {
+	shifted := int(rowNum)
+
+	for _, v := range s {
+		if rowNum > v.afterRow {
+			shifted += v.n
+		}
+	}
+
+	return uint32(shifted)
+}
+
+// removes tells the template row is inside the rows removed by the blocks.
+func (s loopRowShifts) removes(rowNum uint32) bool {
+	for _, v := range s {
+		if v.n < 0 && rowNum <= v.afterRow && int(rowNum) > int(v.afterRow)+v.n {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s loopRowShifts) shiftRef(ref string) string {
+	r, err := reference.ParseCellReference(ref)
+	if err != nil {
+		return ref
+	}
+
+	return fmt.Sprintf("%s%d", r.Column, s.shift(r.RowIdx))
+}
+
+// readPlaceholderLoops reads the repeated rows of the loops into the slice fields,
+// and returns the row shifts for the placeholders after the loops and the blocks removed.
+// The blocks are matched in the order of the rows with the loops, so the shifts of the loops above are known.
+func (x *Xlsx) readPlaceholderLoops(loops []placeholderLoop, blocks []placeholderBlock,
+	fields []reflect.StructField, v reflect.Value,
+) (loopRowShifts, error) {
+	shifts := make(loopRowShifts, 0, len(loops))
+	rows := make(map[uint32]spreadsheet.Row)
+
+	for _, row := range x.currentSheet.Rows() {
+		rows[row.RowNumber()] = row
+	}
+
+	nextBlock := 0
+	readBlocks := func(toRow uint32) error {
+		for ; nextBlock < len(blocks) && blocks[nextBlock].Start <= toRow; nextBlock++ {
+			b := blocks[nextBlock]
+			if shifts.removes(b.Start) {
+				continue
+			}
+
+			if kept, err := x.matchBlock(b, loops, fields, shifts, rows); err != nil {
+				return err
+			} else if !kept {
+				shifts = append(shifts, rowShift{afterRow: b.End, n: -int(b.End - b.Start + 1)})
+			}
+		}
+
+		return nil
+	}
+
+	for _, l := range loops {
+		if err := readBlocks(l.RowNum); err != nil {
+			return nil, err
+		}
+
+		f, ok := findPlaceholderField(fields, l.Name)
+		if !ok || shifts.removes(l.RowNum) {
+			continue
+		}
+
+		startRow := shifts.shift(l.RowNum)
+		fieldTypes := loopFieldTypes(reflect.Zero(f.Type))
+		items := reflect.MakeSlice(f.Type, 0, 1)
+
+		for rowNum := startRow; ; rowNum++ {
+			if err := x.ctxErr(); err != nil {
+				return nil, err
+			}
+
+			row, ok := rows[rowNum]
+			if !ok {
+				break
+			}
+
+			vars, ok, err := x.matchLoopRow(l, row, fieldTypes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read row %d of loop %s: %w", rowNum, l.Name, err)
+			}
+
+			if !ok {
+				break
+			}
+
+			elem, err := newLoopElem(f.Type.Elem(), vars)
+			if err != nil {
+				return nil, err
+			}
+
+			items = reflect.Append(items, elem)
+			x.rowRead(rowNum)
+		}
+
+		v.FieldByIndex(f.Index).Set(items)
+
+		// the template row of the empty loop is removed on writing.
+		if n := items.Len(); n != 1 {
+			shifts = append(shifts, rowShift{afterRow: l.RowNum, n: n - 1})
+		}
+	}
+
+	if err := readBlocks(math.MaxUint32); err != nil {
+		return nil, err
+	}
+
+	return shifts, nil
+}
+
+// matchLoopRow parses the vars of the row by the loop template row,
+// the row is not matched when it matches the next template row, or any cell is out of the template cells,
+// or the texts are not matched, or the values of the placeholders are all empty.
+// The errors of the matched values like the filter parsing failures are returned.
+func (x *Xlsx) matchLoopRow(l placeholderLoop, row spreadsheet.Row,
+	fieldTypes map[string]reflect.StructField,
+) (map[string]string, bool, error) {
+	if len(l.Next) > 0 {
+		if next, err := x.matchNextRow(l.Next, row); err != nil || next {
+			return nil, false, err
+		}
+	}
+
+	columns := make(map[string]bool, len(l.Cells))
+	for _, c := range l.Cells {
+		columns[c.Column] = true
+	}
+
+	for _, cell := range x.rowCells(row) {
+		if col, err := cell.Column(); err == nil && !columns[col] && GetCellString(cell) != "" {
+			return nil, false, nil
+		}
+	}
+
+	vars := make(map[string]string)
+	empty := true
+
+	var matchErr error
+
+	for _, c := range l.Cells {
+		s := x.placeholderCellString(row.Cell(c.Column), c.PlaceholderValue, fieldTypes)
+
+		if !c.HasPlaceholders() {
+			if s != c.Content {
+				return nil, false, nil
+			}
+
+			continue
+		}
+
+		cellVars, ok, err := matchPlaceholder(c.PlaceholderValue, s)
+		if err != nil {
+			matchErr = firstError(matchErr, err)
+			continue
+		} else if !ok {
+			return nil, false, nil
+		}
+
+		for k, v := range cellVars {
+			vars[k] = v
+			empty = empty && v == ""
+		}
+	}
+
+	if matchErr != nil {
+		return nil, false, matchErr
+	}
+
+	return vars, !empty, nil
+}
+
+// matchPlaceholder matches the content by the placeholder value, the mismatch tells the content is not matched,
+// and the other errors like the ambiguous matches and the filter parsing failures are returned.
+// The callers report the errors only when all the cells of the row are matched,
+// so the rows out of the loops or the blocks are not taken as the bad values.
+func matchPlaceholder(pl PlaceholderValue, content string) (map[string]string, bool, error) {
+	vars, err := pl.Match(content)
+	if errors.Is(err, ErrPlaceholderMismatch) {
+		return nil, false, nil
+	}
+
+	return vars, err == nil, err
+}
+
+func firstError(err, next error) error {
+	if err != nil {
+		return err
+	}
+
+	return next
+}
+
+// newLoopElem creates the loop element of the type with the vars, the pointee is allocated for the pointer type.
+func newLoopElem(t reflect.Type, vars map[string]string) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(t.Elem())
+		if err := setElemPlaceholderVars(ptr.Elem(), vars); err != nil {
+			return reflect.Value{}, err
+		}
+
+		return ptr, nil
+	}
+
+	elem := reflect.New(t).Elem()
+	if err := setElemPlaceholderVars(elem, vars); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return elem, nil
+}
+
+func setElemPlaceholderVars(elem reflect.Value, vars map[string]string) error {
+	if elem.Kind() != reflect.Struct {
+		s, ok := vars["."]
+		if !ok {
+			return nil
+		}
+
+		v, err := castMapValue(s, elem.Type(), "")
+		if err != nil {
+			return err
+		}
+
+		elem.Set(v)
+
+		return nil
+	}
+
+	for _, f := range exportedFields(elem.Type()) {
+		if err := setPlaceholderVar(elem, f, ".", vars); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchNextRow tells whether the row matches all the texts of the template cells,
+// the cells with only placeholders are not enough to match.
+func (x *Xlsx) matchNextRow(cells []loopCell, row spreadsheet.Row) (bool, error) {
+	hasText := false
+
+	var matchErr error
+
+	for _, c := range cells {
+		for _, p := range c.Parts {
+			hasText = hasText || p.Var == ""
+		}
+
+		s := x.getCellString(row.Cell(c.Column))
+
+		if !c.HasPlaceholders() && s != c.Content {
+			return false, nil
+		}
+
+		if _, ok, err := matchPlaceholder(c.PlaceholderValue, s); err != nil {
+			matchErr = firstError(matchErr, err)
+		} else if !ok {
+			return false, nil
+		}
+	}
+
+	if !hasText {
+		return false, nil
+	}
+
+	return matchErr == nil, matchErr
+}
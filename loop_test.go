@@ -0,0 +1,255 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type formItem struct {
+	Name string
+	Qty  int
+	Note string
+}
+
+type orderForm struct {
+	OrderNo string `asPlaceholder:"true"`
+	Items   []formItem
+	Total   int
+}
+
+func TestPlaceholderLoop(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("订单：{{OrderNo}}")
+	sheet.Cell("A2").SetString("名称")
+	sheet.Cell("B2").SetString("数量")
+	sheet.Cell("A3").SetString("{{range .Items}}{{.Name}}")
+	sheet.Cell("B3").SetString("{{.Qty}}")
+	sheet.Cell("C3").SetString("{{.Note}}{{end}}")
+	sheet.AddMergedCells("C3", "D3")
+	sheet.Cell("A4").SetString("合计：{{Total}}")
+	sheet.Cell("A5").SetString("备注")
+	sheet.AddMergedCells("A5", "B5")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	order := orderForm{
+		OrderNo: "D001",
+		Items: []formItem{
+			{Name: "苹果", Qty: 3, Note: "红"},
+			{Name: "香蕉", Qty: 4},
+			{Name: "梨", Qty: 5, Note: "大"},
+		},
+		Total: 12,
+	}
+
+	assert.Nil(t, x.Write(order))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "订单：D001", sheet.Cell("A1").GetString())
+	assert.Equal(t, "苹果", sheet.Cell("A3").GetString())
+	assert.Equal(t, "香蕉", sheet.Cell("A4").GetString())
+	assert.Equal(t, "5", sheet.Cell("B5").GetString())
+	assert.Equal(t, "大", sheet.Cell("C5").GetString())
+	assert.Equal(t, "合计：12", sheet.Cell("A6").GetString())
+	assert.Equal(t, "备注", sheet.Cell("A7").GetString())
+
+	refs := make([]string, 0)
+	for _, mc := range sheet.MergedCells() {
+		refs = append(refs, mc.Reference())
+	}
+
+	assert.ElementsMatch(t, []string{"C3:D3", "A7:B7", "C4:D4", "C5:D5"}, refs)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read orderForm
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, order, read)
+}
+
+func TestPlaceholderLoopReferences(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("订单")
+	sheet.Cell("A2").SetString("名称")
+	sheet.Cell("B2").SetString("数量")
+	sheet.Cell("A3").SetString("{{range .Items}}{{.Name}}")
+	sheet.Cell("B3").SetString("{{.Qty}}{{end}}")
+	sheet.Cell("A4").SetString("合计")
+	sheet.Cell("B4").SetFormulaRaw("SUM(B3:B3)")
+	sheet.Cell("C4").SetFormulaRaw("B4*2")
+	sheet.Cell("D4").SetFormulaRaw(`IF(B4>0,"B3","")`)
+	sheet.AddDataValidation().SetRange("B3:B3")
+	sheet.AddConditionalFormatting([]string{"B4"}).AddRule().SetType(sml.ST_CfTypeDataBar)
+	wb.AddDefinedName("_xlnm.Print_Area", "'订单'!$A$1:$D$4")
+
+	other := wb.AddSheet()
+	other.Cell("A1").SetFormulaRaw("订单!B4")
+	other.Cell("A2").SetFormulaRaw("SUM('订单'!B3:B3)")
+	other.Cell("A3").SetFormulaRaw("B4")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(orderForm{Items: []formItem{{Name: "苹果", Qty: 3}, {Name: "香蕉", Qty: 4}, {Name: "梨", Qty: 5}}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "合计", sheet.Cell("A6").GetString())
+	assert.Equal(t, "SUM(B3:B5)", sheet.Cell("B6").GetFormula())
+	assert.Equal(t, "B6*2", sheet.Cell("C6").GetFormula())
+	assert.Equal(t, `IF(B6>0,"B3","")`, sheet.Cell("D6").GetFormula())
+	assert.Equal(t, sml.ST_Sqref{"B3:B5"}, sheet.X().DataValidations.DataValidation[0].SqrefAttr)
+	assert.Equal(t, sml.ST_Sqref{"B6"}, *sheet.X().ConditionalFormatting[0].SqrefAttr)
+	assert.Equal(t, "'订单'!$A$1:$D$6", out.DefinedNames()[0].Content())
+
+	other = out.Sheets()[1]
+	assert.Equal(t, "订单!B6", other.Cell("A1").GetFormula())
+	assert.Equal(t, "SUM('订单'!B3:B5)", other.Cell("A2").GetFormula())
+	assert.Equal(t, "B4", other.Cell("A3").GetFormula())
+}
+
+func TestPlaceholderLoopMatchError(t *testing.T) {
+	newSheet := func(cells map[string]string) []byte {
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+
+		for ref, v := range cells {
+			sheet.Cell(ref).SetString(v)
+		}
+
+		return saveWorkbook(t, wb)
+	}
+
+	tmpl := newSheet(map[string]string{
+		"A1": "订单：{{OrderNo}}",
+		"A2": "{{range .Items}}{{.Name}}",
+		"B2": "{{.Qty | money}}{{end}}",
+		"A3": "合计",
+	})
+	data := newSheet(map[string]string{"A1": "订单：D001", "A2": "苹果", "B2": "三个", "A3": "合计"})
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(data))
+	defer x.Close()
+
+	var order orderForm
+
+	err := x.Read(&order)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bad money 三个")
+}
+
+func TestPlaceholderLoopEmpty(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("订单：{{OrderNo}}")
+	sheet.Cell("A2").SetString("{{range .Items}}{{.Name}}")
+	sheet.Cell("B2").SetString("{{.Qty}}{{end}}")
+	sheet.Cell("A3").SetString("合计：{{Total}}")
+	sheet.Cell("B3").SetFormulaRaw("SUM(B2:B2)")
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	order := orderForm{OrderNo: "D001", Items: []formItem{}, Total: 0}
+	assert.Nil(t, x.Write(order))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	// no element, no row: the row after the loop is shifted up.
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "合计：0", sheet.Cell("A2").GetString())
+	assert.Equal(t, "SUM(#REF!)", sheet.Cell("B2").GetFormula())
+	assert.Equal(t, 2, len(sheet.Rows()))
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read orderForm
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, order, read)
+}
+
+type pointerOrderForm struct {
+	OrderNo string `asPlaceholder:"true"`
+	Items   []*formItem
+}
+
+func TestPlaceholderLoopPointers(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("订单：{{OrderNo}}")
+	sheet.Cell("A2").SetString("{{range .Items}}{{.Name}}")
+	sheet.Cell("B2").SetString("{{.Qty}}{{end}}")
+	sheet.Cell("A3").SetString("合计")
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	// the nil element is written as an empty row.
+	assert.Nil(t, x.Write(pointerOrderForm{OrderNo: "D001", Items: []*formItem{{Name: "苹果", Qty: 3}, nil, {Name: "梨", Qty: 5}}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "苹果", sheet.Cell("A2").GetString())
+	assert.Equal(t, "", sheet.Cell("A3").GetString())
+	assert.Equal(t, "梨", sheet.Cell("A4").GetString())
+	assert.Equal(t, "合计", sheet.Cell("A5").GetString())
+
+	data := spreadsheet.New()
+	dataSheet := data.AddSheet()
+	dataSheet.Cell("A1").SetString("订单：D001")
+	dataSheet.Cell("A2").SetString("苹果")
+	dataSheet.Cell("B2").SetNumber(3)
+	dataSheet.Cell("A3").SetString("梨")
+	dataSheet.Cell("B3").SetNumber(5)
+	dataSheet.Cell("A4").SetString("合计")
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(saveWorkbook(t, data)))
+	defer x2.Close()
+
+	var read pointerOrderForm
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, pointerOrderForm{OrderNo: "D001", Items: []*formItem{{Name: "苹果", Qty: 3}, {Name: "梨", Qty: 5}}}, read)
+}
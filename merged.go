@@ -0,0 +1,81 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// WithMergedCellsFill resolves the merged cells when reading,
+// every cell inside a merged range gets the value of the top left cell of the range,
+// which makes Read the inverse of Write with WithMergeColsMode.
+func WithMergedCellsFill() ReadOptionFn {
+	return func(o *ReadOption) {
+		o.MergedCellsFill = true
+	}
+}
+
+// mergedRange is a merged range parsed from the sheet.
+type mergedRange struct {
+	from, to reference.CellReference
+}
+
+func (m mergedRange) contains(ref reference.CellReference) bool {
+	return ref.RowIdx >= m.from.RowIdx && ref.RowIdx <= m.to.RowIdx &&
+		ref.ColumnIdx >= m.from.ColumnIdx && ref.ColumnIdx <= m.to.ColumnIdx
+}
+
+// resolveMergedCell returns the top left cell of the merged range which contains c,
+// or c itself when it is not merged or the merged cells filling is off.
+func (x *Xlsx) resolveMergedCell(c spreadsheet.Cell) spreadsheet.Cell {
+	if !x.readOption.MergedCellsFill {
+		return c
+	}
+
+	if x.mergedRanges == nil {
+		x.mergedRanges = collectMergedRanges(x.currentSheet)
+	}
+
+	ref, err := reference.ParseCellReference(c.Reference())
+	if err != nil {
+		return c
+	}
+
+	// the ranges are sorted by the first rows, the ranges below the cell are skipped.
+	for _, m := range x.mergedRanges {
+		if m.from.RowIdx > ref.RowIdx {
+			break
+		}
+
+		if m.contains(ref) {
+			if m.from.RowIdx == ref.RowIdx && m.from.ColumnIdx == ref.ColumnIdx {
+				return c
+			}
+
+			return x.currentSheet.Cell(fmt.Sprintf("%s%d", m.from.Column, m.from.RowIdx))
+		}
+	}
+
+	return c
+}
+
+// collectMergedRanges collects the merged ranges of the sheet sorted by the first rows,
+// the ranges are kept as they are instead of expanding to the cells, which may be a whole sheet.
+func collectMergedRanges(sheet spreadsheet.Sheet) []mergedRange {
+	ranges := make([]mergedRange, 0)
+
+	for _, mc := range sheet.MergedCells() {
+		from, to, err := reference.ParseRangeReference(mc.Reference())
+		if err != nil {
+			continue
+		}
+
+		ranges = append(ranges, mergedRange{from: from, to: to})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].from.RowIdx < ranges[j].from.RowIdx })
+
+	return ranges
+}
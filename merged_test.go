@@ -0,0 +1,74 @@
+package xlsx_test
+
+import (
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestReadMergedCells(t *testing.T) {
+	// a hand-made report, only the top cell of the vertically merged cells holds a value.
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("会员")
+
+	for i, row := range [][]float64{{100, 50, 50}, {0, 60, 0}, {200, 60, 140}, {0, 0, 0}} {
+		if i == 0 {
+			titles := sheet.AddRow()
+			titles.AddCell().SetString("会员总数")
+			titles.AddCell().SetString("其中：新增")
+			titles.AddCell().SetString("其中：有效")
+		}
+
+		r := sheet.AddRow()
+
+		for _, v := range row {
+			if c := r.AddCell(); v != 0 {
+				c.SetNumber(v)
+			}
+		}
+	}
+
+	sheet.AddMergedCells("A2", "A3")
+	sheet.AddMergedCells("C2", "C3")
+	sheet.AddMergedCells("A4", "A5")
+	sheet.AddMergedCells("B3", "B5")
+	sheet.AddMergedCells("C4", "C5")
+
+	x, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	var stats []memberStat
+
+	assert.Nil(t, x.Read(&stats))
+	assert.Equal(t, memberStat{New: 60}, stats[1])
+
+	assert.Nil(t, x.Read(&stats, xlsx.WithMergedCellsFill()))
+	assert.Equal(t, []memberStat{
+		{Total: 100, New: 50, Effective: 50},
+		{Total: 100, New: 60, Effective: 50},
+		{Total: 200, New: 60, Effective: 140},
+		{Total: 200, New: 60, Effective: 140},
+	}, stats)
+}
+
+func TestReadWholeSheetMerged(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("会员总数")
+	sheet.Cell("B1").SetString("其中：新增")
+	sheet.Cell("C1").SetString("其中：有效")
+	sheet.Cell("A2").SetNumber(100)
+	sheet.Cell("C2")
+	sheet.AddMergedCells("A2", "XFD1048576")
+
+	x, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	var stats []memberStat
+
+	assert.Nil(t, x.Read(&stats, xlsx.WithMergedCellsFill()))
+	assert.Equal(t, []memberStat{{Total: 100, New: 100, Effective: 100}}, stats)
+}
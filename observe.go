@@ -0,0 +1,72 @@
+package xlsx
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Hooks defines the callbacks to observe the reading and writing, like emitting the metrics and traces
+// around the import and export jobs, the nil callbacks are skipped.
+// The row hooks cover the struct rows, the map rows, the tables, the vertical layout and the placeholder loops,
+// the placeholder cells outside the loops are not rows and only the phases are observed for them.
+type Hooks struct {
+	// RowRead is called after a data row is read, with the sheet name and the row number,
+	// like a row of beans or a table, a loop row of the placeholder template,
+	// or a label row of the vertical layout once all the records are read.
+	RowRead func(sheet string, rowNum uint32)
+	// RowWritten is called after a row is written, with the sheet name and the row number,
+	// like a row of beans or maps, a subtotal or totals row, an expanded loop row of the placeholder template,
+	// or a label row of the vertical layout once all the records are written.
+	RowWritten func(sheet string, rowNum uint32)
+	// CellFailed is called when a cell fails to read or write, like a bad number or a bad image,
+	// the error is also returned by Read or Write, or logged as a warning
+	// when a typed placeholder value falls back to text.
+	CellFailed func(sheet, cellRef string, err error)
+	// PhaseDone is called when a phase of read, write or save is done, with the duration and the error.
+	PhaseDone func(phase string, d time.Duration, err error)
+}
+
+// WithLogger defines the structured logger for the warnings of this package, default slog.Default(),
+// like the skipped cells, the unknown filters and the missing loop fields.
+// The logs inside unioffice itself are global, and can be disabled by unioffice.DisableLogging.
+func WithLogger(logger *slog.Logger) OptionFn {
+	return func(o *Option) { o.Logger = logger }
+}
+
+// WithHooks defines the callbacks to observe the reading and writing.
+func WithHooks(hooks Hooks) OptionFn {
+	return func(o *Option) { o.Hooks = hooks }
+}
+
+func (x *Xlsx) logger() *slog.Logger {
+	if x.option.Logger != nil {
+		return x.option.Logger
+	}
+
+	return slog.Default()
+}
+
+// phaseDone calls the PhaseDone hook with the duration since start, it is deferred with the named error result.
+func (x *Xlsx) phaseDone(phase string, start time.Time, err *error) {
+	if h := x.option.Hooks.PhaseDone; h != nil {
+		h(phase, time.Since(start), *err)
+	}
+}
+
+func (x *Xlsx) rowRead(rowNum uint32) {
+	if h := x.option.Hooks.RowRead; h != nil {
+		h(x.currentSheet.Name(), rowNum)
+	}
+}
+
+func (x *Xlsx) rowWritten(rowNum uint32) {
+	if h := x.option.Hooks.RowWritten; h != nil {
+		h(x.currentSheet.Name(), rowNum)
+	}
+}
+
+func (x *Xlsx) cellFailed(cellRef string, err error) {
+	if h := x.option.Hooks.CellFailed; h != nil {
+		h(x.currentSheet.Name(), cellRef, err)
+	}
+}
@@ -0,0 +1,149 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestHooks(t *testing.T) {
+	var (
+		written, read []uint32
+		phases        []string
+		failedCells   []string
+	)
+
+	hooks := xlsx.Hooks{
+		RowRead:    func(_ string, rowNum uint32) { read = append(read, rowNum) },
+		RowWritten: func(_ string, rowNum uint32) { written = append(written, rowNum) },
+		CellFailed: func(sheet, cellRef string, _ error) { failedCells = append(failedCells, sheet+"!"+cellRef) },
+		PhaseDone: func(phase string, d time.Duration, _ error) {
+			assert.True(t, d >= 0)
+			phases = append(phases, phase)
+		},
+	}
+
+	x, _ := xlsx.New(xlsx.WithHooks(hooks))
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]memberStat{{Total: 100, New: 50, Effective: 50}, {Total: 200, New: 60, Effective: 140}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+	assert.Equal(t, []uint32{2, 3}, written)
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()), xlsx.WithHooks(hooks))
+	defer x2.Close()
+
+	var stats []memberStat
+
+	assert.Nil(t, x2.Read(&stats))
+	assert.Equal(t, []uint32{2, 3}, read)
+	assert.Equal(t, []string{"write", "save", "read"}, phases)
+
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("会员")
+	sheet.Cell("A1").SetString("会员总数")
+	sheet.Cell("B1").SetString("其中：新增")
+	sheet.Cell("C1").SetString("其中：有效")
+	sheet.Cell("A2").SetString("abc")
+
+	x3, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)), xlsx.WithHooks(hooks))
+	defer x3.Close()
+
+	assert.NotNil(t, x3.Read(&stats))
+	assert.Equal(t, []string{"会员!A2"}, failedCells)
+}
+
+func TestWithLogger(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("{{Name | nosuch}}")
+
+	var logs bytes.Buffer
+
+	x, _ := xlsx.New(xlsx.WithTemplate(saveWorkbook(t, wb)), xlsx.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(person{Name: "张三"}))
+	assert.Contains(t, logs.String(), "unknown placeholder filter")
+	assert.Contains(t, logs.String(), "filter=nosuch")
+}
+
+func TestHooksPaths(t *testing.T) {
+	var written, read []uint32
+
+	hooks := xlsx.Hooks{
+		RowRead:    func(_ string, rowNum uint32) { read = append(read, rowNum) },
+		RowWritten: func(_ string, rowNum uint32) { written = append(written, rowNum) },
+	}
+
+	x, _ := xlsx.New(xlsx.WithHooks(hooks))
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]map[string]interface{}{{"a": 1}, {"a": 2}}))
+	assert.Equal(t, []uint32{2, 3}, written)
+
+	var mapsBuf bytes.Buffer
+
+	assert.Nil(t, x.Save(&mapsBuf))
+
+	x1, _ := xlsx.New(xlsx.WithExcel(mapsBuf.Bytes()), xlsx.WithHooks(hooks))
+	defer x1.Close()
+
+	table, err := x1.ReadTable()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(table.Rows))
+	assert.Equal(t, []uint32{2, 3}, read)
+
+	written = nil
+	x2, _ := xlsx.New(xlsx.WithHooks(hooks))
+	defer x2.Close()
+
+	assert.Nil(t, x2.Write([]memberStat{{Total: 100}, {Total: 200}}, xlsx.WithTotalsRow("合计")))
+	assert.Equal(t, []uint32{2, 3, 4}, written)
+
+	written, read = nil, nil
+	x3, _ := xlsx.New(xlsx.WithTemplate(createOrderTemplate(t)), xlsx.WithHooks(hooks))
+	defer x3.Close()
+
+	assert.Nil(t, x3.Write(orderForm{OrderNo: "D001", Items: []formItem{{Name: "苹果", Qty: 3}, {Name: "梨", Qty: 5}}}))
+	assert.Equal(t, []uint32{2, 3}, written)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x3.Save(&buf))
+
+	x4, _ := xlsx.New(xlsx.WithTemplate(createOrderTemplate(t)), xlsx.WithExcel(buf.Bytes()), xlsx.WithHooks(hooks))
+	defer x4.Close()
+
+	var form orderForm
+
+	assert.Nil(t, x4.Read(&form))
+	assert.Equal(t, 2, len(form.Items))
+	assert.Equal(t, []uint32{2, 3}, read)
+}
+
+func TestWithLoggerRowCells(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("会员总数")
+	sheet.Row(1).X().C = append(sheet.Row(1).X().C, &sml.CT_Cell{})
+
+	var logs bytes.Buffer
+
+	x, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)), xlsx.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	defer x.Close()
+
+	_, err := x.ReadTable()
+	assert.Nil(t, err)
+	assert.Contains(t, logs.String(), "RAttr is nil for a cell")
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"log/slog"
+	"os"
 
 	"github.com/unidoc/unioffice/spreadsheet"
 )
@@ -18,6 +20,15 @@ func createOption(optionFns []OptionFn) *Option {
 		}
 	}
 
+	// the workbooks are opened after all the options are applied, so the limits apply to them.
+	for _, open := range option.opens {
+		if err := open(option); err != nil && option.err == nil {
+			option.err = err
+		}
+	}
+
+	option.opens = nil
+
 	return option
 }
 
@@ -26,6 +37,27 @@ type Option struct {
 	TemplateWorkbook, Workbook *spreadsheet.Workbook
 
 	Validations map[string][]string
+	Styles      map[string]Style
+
+	ConditionalFormats map[string][]ConditionalFormat
+
+	// PlaceholderDelims are the delimiters of the placeholders, default {{ and }}.
+	PlaceholderDelims PlaceholderDelims
+	// PlaceholderFilters are the custom filters of the placeholder pipes, over the built-in filters.
+	PlaceholderFilters map[string]PlaceholderFilter
+	// StrictPlaceholders makes the placeholder mode fail on the template placeholders without the matching fields.
+	StrictPlaceholders bool
+
+	// Logger is the structured logger for the warnings, default slog.Default().
+	Logger *slog.Logger
+	// Hooks are the callbacks to observe the reading and writing.
+	Hooks Hooks
+
+	// Limits are the safety limits of the workbooks read, like the uploads.
+	Limits Limits
+
+	opens []func(*Option) error
+	err   error
 }
 
 // OptionFn defines the func to change the option.
@@ -36,14 +68,19 @@ type OptionFn func(*Option)
 // 1. a string for direct template excel file name
 // 2. a []byte for the content of template excel which loaded in advance, like use packr2 to read.
 // 3. a io.Reader.
+// 4. a *os.File, a FileInFS like an embedded file, or a SizedReaderAt, which are read without the full buffering.
+// The template is opened by New, which returns the error of opening it.
 func WithTemplate(template interface{}) OptionFn {
-	wb, err := parseExcel(template)
-	if err != nil {
-		log.Printf("W! failed to open template excel %v", err)
-		return nil
-	}
+	return withOpen(func(o *Option) error {
+		wb, err := parseExcel(template, o.Limits)
+		if err != nil {
+			return fmt.Errorf("failed to open template excel: %w", err)
+		}
 
-	return func(o *Option) { o.TemplateWorkbook = wb }
+		o.TemplateWorkbook = wb
+
+		return nil
+	})
 }
 
 // WithExcel defines the input excel file for reading.
@@ -51,38 +88,202 @@ func WithTemplate(template interface{}) OptionFn {
 // 1. a string for direct excel file name
 // 2. a []byte for the content of excel which loaded in advance, like use packr2 to read.
 // 3. a io.Reader.
+// 4. a *os.File, a FileInFS like an uploaded file saved on disk, or a SizedReaderAt,
+// which are read without the full buffering.
+// The excel is opened by New, which returns the error of opening it.
 func WithExcel(excel interface{}) OptionFn {
-	wb, err := parseExcel(excel)
-	if err != nil {
-		log.Printf("W! failed to open excel %v", err)
+	return withOpen(func(o *Option) error {
+		wb, err := parseExcel(excel, o.Limits)
+		if err != nil {
+			return fmt.Errorf("failed to open excel: %w", err)
+		}
+
+		o.Workbook = wb
+
 		return nil
-	}
+	})
+}
+
+// withOpen defers opening the workbook until all the options are applied, the first error is returned by New.
+func withOpen(open func(*Option) error) OptionFn {
+	return func(o *Option) { o.opens = append(o.opens, open) }
+}
+
+var (
+	// ErrUnknownExcelError defines the the unknown excel file format error.
+	ErrUnknownExcelError = fmt.Errorf("unknown excel file format")
+	// ErrCorruptExcel defines the error of the xlsx file which is a broken zip, like a truncated upload.
+	ErrCorruptExcel = fmt.Errorf("corrupt excel zip")
+	// ErrNotXlsx defines the error of the file in the other formats, like the legacy .xls or a renamed CSV.
+	ErrNotXlsx = fmt.Errorf("not an xlsx file")
+	// ErrEncryptedExcel defines the error of the excel file encrypted by a password.
+	ErrEncryptedExcel = fmt.Errorf("encrypted excel")
+	// ErrMissingFormField defines the error of the upload request without the file form field.
+	ErrMissingFormField = fmt.Errorf("missing form field")
+)
 
-	return func(o *Option) { o.Workbook = wb }
+// FileInFS defines the excel file in the file system, like an embed.FS.
+type FileInFS struct {
+	FS   fs.FS
+	Name string
 }
 
-// ErrUnknownExcelError defines the the unknown excel file format error.
-var ErrUnknownExcelError = fmt.Errorf("unknown excel file format")
+// SizedReaderAt defines the excel content of the Size bytes read by the ReaderAt.
+type SizedReaderAt struct {
+	io.ReaderAt
+	Size int64
+}
 
-func parseExcel(f interface{}) (wb *spreadsheet.Workbook, err error) {
+func parseExcel(f interface{}, limits Limits) (wb *spreadsheet.Workbook, err error) {
 	var bs []byte
 
 	switch ft := f.(type) {
 	case string:
-		return spreadsheet.Open(ft)
+		file, err := os.Open(ft)
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		return readFile(file, limits)
 	case []byte:
-		return spreadsheet.Read(bytes.NewReader(ft), int64(len(ft)))
+		return readExcel(bytes.NewReader(ft), int64(len(ft)), limits)
+	case SizedReaderAt:
+		return readExcel(ft.ReaderAt, ft.Size, limits)
+	case *os.File:
+		return readFile(ft, limits)
+	case FileInFS:
+		return readFileInFS(ft, limits)
 	case io.Reader:
 		if bs, err = io.ReadAll(ft); err != nil {
 			return nil, err
 		}
 
-		return spreadsheet.Read(bytes.NewReader(bs), int64(len(bs)))
+		return readExcel(bytes.NewReader(bs), int64(len(bs)), limits)
 	default:
 		return nil, ErrUnknownExcelError
 	}
 }
 
+// readExcel reads the workbook from r within the limits, the error is typed by the format of the content.
+func readExcel(r io.ReaderAt, size int64, limits Limits) (*spreadsheet.Workbook, error) {
+	if err := limits.checkZip(r, size); err != nil {
+		return nil, err
+	}
+
+	wb, err := spreadsheet.Read(r, size)
+	if err != nil {
+		return nil, excelError(r, size, limits, err)
+	}
+
+	if err := limits.checkSharedStrings(wb); err != nil {
+		_ = wb.Close()
+		return nil, err
+	}
+
+	return wb, nil
+}
+
+// readFile reads the workbook from the file which is an io.ReaderAt with the size by Stat,
+// the other files are read fully into the memory.
+func readFile(f fs.File, limits Limits) (*spreadsheet.Workbook, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if r, ok := f.(io.ReaderAt); ok {
+		return readExcel(r, fi.Size(), limits)
+	}
+
+	bs, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return readExcel(bytes.NewReader(bs), int64(len(bs)), limits)
+}
+
+func readFileInFS(f FileInFS, limits Limits) (*spreadsheet.Workbook, error) {
+	file, err := f.FS.Open(f.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return readFile(file, limits)
+}
+
+// nolint:gochecknoglobals
+var (
+	zipMagic = []byte("PK\x03\x04")
+	oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	// encryptedPackage is the name of the stream in UTF-16LE in the compound file of the encrypted xlsx.
+	encryptedPackage = []byte("E\x00n\x00c\x00r\x00y\x00p\x00t\x00e\x00d\x00P\x00a\x00c\x00k\x00a\x00g\x00e\x00")
+)
+
+// excelError types the error of reading the workbook by the magic bytes of the content.
+// The encrypted xlsx and the legacy .xls are both the compound files, which are told by the EncryptedPackage stream.
+func excelError(r io.ReaderAt, size int64, limits Limits, err error) error {
+	header := make([]byte, len(oleMagic))
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, zipMagic):
+		return fmt.Errorf("%w: %w", ErrCorruptExcel, err)
+	case bytes.Equal(header, oleMagic):
+		if limits.MaxUncompressedSize > 0 && size > limits.MaxUncompressedSize {
+			size = limits.MaxUncompressedSize
+		}
+
+		if containsBytes(io.NewSectionReader(r, 0, size), encryptedPackage) {
+			return fmt.Errorf("%w: %w", ErrEncryptedExcel, err)
+		}
+
+		return fmt.Errorf("%w: legacy xls file", ErrNotXlsx)
+	default:
+		return fmt.Errorf("%w: %w", ErrNotXlsx, err)
+	}
+}
+
+// scanChunkSize is the size of the chunks to scan the compound files for the EncryptedPackage stream.
+const scanChunkSize = 64 * 1024
+
+// containsBytes tells whether r contains sep by scanning the fixed size chunks,
+// the tail of the previous chunk is kept to find sep across the chunks.
+func containsBytes(r io.Reader, sep []byte) bool {
+	buf := make([]byte, len(sep)-1+scanChunkSize)
+	kept := 0
+
+	for {
+		n, err := io.ReadFull(r, buf[kept:])
+		if bytes.Contains(buf[:kept+n], sep) {
+			return true
+		}
+
+		if err != nil {
+			return false
+		}
+
+		kept = copy(buf, buf[kept+n-(len(sep)-1):kept+n])
+	}
+}
+
+// WithPlaceholderDelims defines the delimiters of the placeholders, like ${ and } for ${Name},
+// which avoids the clash with the literal double braces in the templates, like JSON samples.
+func WithPlaceholderDelims(left, right string) OptionFn {
+	return func(o *Option) { o.PlaceholderDelims = PlaceholderDelims{Left: left, Right: right} }
+}
+
+// WithStrictPlaceholders makes the placeholder mode fail with ErrUnknownPlaceholder
+// when the template has placeholders without the matching fields.
+func WithStrictPlaceholders() OptionFn {
+	return func(o *Option) { o.StrictPlaceholders = true }
+}
+
 // WithValidations defines the validations for the cells.
 func WithValidations(v map[string][]string) OptionFn {
 	return func(o *Option) { o.Validations = v }
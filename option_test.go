@@ -1,11 +1,17 @@
 package xlsx_test
 
 import (
+	"bytes"
+	"embed"
 	"errors"
+	"io/fs"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/bingoohuang/xlsx"
 	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
 )
 
 type errReader int
@@ -15,8 +21,106 @@ func (errReader) Read(_ []byte) (n int, err error) {
 }
 
 func TestWithExcel(t *testing.T) {
-	assert.Nil(t, xlsx.WithExcel(errReader(0)))
-	assert.Nil(t, xlsx.WithExcel("README.md"))
-	assert.Nil(t, xlsx.WithTemplate("README.md"))
-	assert.Nil(t, xlsx.WithTemplate(t))
+	for _, fn := range []xlsx.OptionFn{
+		xlsx.WithExcel(errReader(0)),
+		xlsx.WithExcel("README.md"),
+		xlsx.WithTemplate("README.md"),
+		xlsx.WithTemplate(t),
+	} {
+		x, err := xlsx.New(fn)
+		assert.Nil(t, x)
+		assert.NotNil(t, err)
+	}
+
+	_, err := xlsx.New(xlsx.WithTemplate(t))
+	assert.True(t, errors.Is(err, xlsx.ErrUnknownExcelError))
+}
+
+func TestExcelFormatErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/template.xlsx")
+	assert.Nil(t, err)
+
+	ole := []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	legacy := append(append([]byte{}, ole...), make([]byte, 512)...)
+	encrypted := append(append([]byte{}, legacy...), []byte("E\x00n\x00c\x00r\x00y\x00p\x00t\x00e\x00d\x00"+
+		"P\x00a\x00c\x00k\x00a\x00g\x00e\x00")...)
+
+	for _, c := range []struct {
+		data []byte
+		err  error
+	}{
+		{data: data[:100], err: xlsx.ErrCorruptExcel},
+		{data: []byte("Name,Age\n张三,18\n"), err: xlsx.ErrNotXlsx},
+		{data: legacy, err: xlsx.ErrNotXlsx},
+		{data: encrypted, err: xlsx.ErrEncryptedExcel},
+	} {
+		_, err := xlsx.New(xlsx.WithExcel(c.data))
+		assert.True(t, errors.Is(err, c.err), err)
+
+		_, err = xlsx.New(xlsx.WithTemplate(bytes.NewReader(c.data)))
+		assert.True(t, errors.Is(err, c.err), err)
+	}
+
+	// the stream name across the scanned chunks is found, the content beyond MaxUncompressedSize is not scanned.
+	spanned := append(append(append([]byte{}, ole...), make([]byte, 64*1024-len(ole)-5)...), encrypted[len(legacy):]...)
+	_, err = xlsx.New(xlsx.WithExcel(spanned))
+	assert.True(t, errors.Is(err, xlsx.ErrEncryptedExcel), err)
+
+	_, err = xlsx.New(xlsx.WithExcel(spanned), xlsx.WithLimits(xlsx.Limits{MaxUncompressedSize: 1024}))
+	assert.True(t, errors.Is(err, xlsx.ErrNotXlsx), err)
+
+	name := t.TempDir() + "/legacy.xlsx"
+	assert.Nil(t, os.WriteFile(name, legacy, 0o600))
+
+	_, err = xlsx.New(xlsx.WithExcel(name))
+	assert.True(t, errors.Is(err, xlsx.ErrNotXlsx), err)
+}
+
+//go:embed testdata/template.xlsx
+var templateFS embed.FS
+
+func TestExcelSources(t *testing.T) {
+	writeTemplate := func(template interface{}) []byte {
+		x, err := xlsx.New(xlsx.WithTemplate(template))
+		assert.Nil(t, err)
+
+		defer x.Close()
+
+		assert.Nil(t, x.Write([]memberStat{{Total: 100, New: 50, Effective: 50}}))
+
+		var buf bytes.Buffer
+
+		assert.Nil(t, x.Save(&buf))
+
+		out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		assert.Nil(t, err)
+
+		var values []string
+		for _, row := range out.Sheets()[0].Rows() {
+			for _, cell := range row.Cells() {
+				values = append(values, cell.GetString())
+			}
+		}
+
+		return []byte(strings.Join(values, ","))
+	}
+
+	expected := writeTemplate("testdata/template.xlsx")
+	assert.NotEmpty(t, expected)
+
+	f, err := os.Open("testdata/template.xlsx")
+	assert.Nil(t, err)
+
+	defer f.Close()
+
+	fi, err := f.Stat()
+	assert.Nil(t, err)
+
+	assert.Equal(t, expected, writeTemplate(f))
+	assert.Equal(t, expected, writeTemplate(xlsx.SizedReaderAt{ReaderAt: f, Size: fi.Size()}))
+	assert.Equal(t, expected, writeTemplate(xlsx.FileInFS{FS: templateFS, Name: "testdata/template.xlsx"}))
+	assert.Equal(t, expected, writeTemplate(xlsx.FileInFS{FS: os.DirFS("testdata"), Name: "template.xlsx"}))
+
+	_, err = xlsx.New(xlsx.WithTemplate(xlsx.FileInFS{FS: templateFS, Name: "missing.xlsx"}))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
 }
@@ -0,0 +1,103 @@
+package xlsx
+
+import (
+	"reflect"
+
+	"github.com/unidoc/unioffice/schema/soo/sml"
+)
+
+// WithRowOutline groups the written rows into the Excel row outline (collapsible levels),
+// the outline level of each row is read from the integer field named levelField of the bean,
+// like 0 for the parent rows and 1 for their children rows below.
+// The children rows are hidden when collapsed is true.
+func WithRowOutline(levelField string, collapsed bool) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.OutlineLevelField = levelField
+		o.OutlineCollapsed = collapsed
+	}
+}
+
+// outlineRows sets the outline levels of the rows from startRow written with the beans,
+// the inserted rows like subtotals are at the top level.
+func (x *Xlsx) outlineRows(option WriteOption, r *run, startRow int) {
+	if option.OutlineLevelField == "" || !r.isSlice {
+		return
+	}
+
+	maxLevel := uint8(0)
+	levels := make([]uint8, len(r.rowBeans))
+
+	for i := range levels {
+		if bean, ok := r.rowBean(i); ok {
+			levels[i] = outlineLevel(bean.FieldByName(option.OutlineLevelField))
+		}
+
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+	}
+
+	if maxLevel == 0 {
+		return
+	}
+
+	for i, level := range levels {
+		row := x.currentSheet.Row(uint32(startRow + i)).X()
+
+		if level > 0 {
+			lvl := level
+			row.OutlineLevelAttr = &lvl
+
+			if option.OutlineCollapsed {
+				hidden := true
+				row.HiddenAttr = &hidden
+			}
+		}
+
+		if option.OutlineCollapsed && i+1 < len(levels) && levels[i+1] > level {
+			collapsed := true
+			row.CollapsedAttr = &collapsed
+		}
+	}
+
+	ws := x.currentSheet.X()
+	if ws.SheetFormatPr == nil {
+		ws.SheetFormatPr = sml.NewCT_SheetFormatPr()
+	}
+
+	ws.SheetFormatPr.OutlineLevelRowAttr = &maxLevel
+
+	if ws.SheetPr == nil {
+		ws.SheetPr = sml.NewCT_SheetPr()
+	}
+
+	// the parent rows are above their children rows.
+	summaryBelow := false
+	ws.SheetPr.OutlinePr = &sml.CT_OutlinePr{SummaryBelowAttr: &summaryBelow}
+}
+
+// maxOutlineLevel is the max outline level supported by Excel.
+const maxOutlineLevel = 7
+
+func outlineLevel(v reflect.Value) uint8 {
+	level := int64(0)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		level = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		level = int64(v.Uint())
+	default:
+		return 0
+	}
+
+	if level < 0 {
+		return 0
+	}
+
+	if level > maxOutlineLevel {
+		return maxOutlineLevel
+	}
+
+	return uint8(level)
+}
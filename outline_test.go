@@ -0,0 +1,44 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type department struct {
+	Name  string `title:"部门"`
+	Count int    `title:"人数"`
+	Level int    `title:"-"`
+}
+
+func TestRowOutline(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]department{
+		{Name: "研发中心", Count: 30},
+		{Name: "前端组", Count: 10, Level: 1},
+		{Name: "后端组", Count: 20, Level: 1},
+		{Name: "市场部", Count: 5},
+	}, xlsx.WithRowOutline("Level", true))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	assert.Equal(t, uint8(1), *sheet.X().SheetFormatPr.OutlineLevelRowAttr)
+	assert.True(t, *sheet.Row(2).X().CollapsedAttr)
+	assert.Nil(t, sheet.Row(2).X().OutlineLevelAttr)
+	assert.Equal(t, uint8(1), *sheet.Row(3).X().OutlineLevelAttr)
+	assert.True(t, sheet.Row(4).IsHidden())
+	assert.False(t, sheet.Row(5).IsHidden())
+}
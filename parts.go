@@ -0,0 +1,367 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unioffice/schema/soo/dml"
+	"github.com/unidoc/unioffice/schema/soo/dml/spreadsheetDrawing"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// placeholderText is a text part of the sheet which may contain placeholders,
+// like a cell, the header and footer, the sheet name, a comment or a paragraph of a text box.
+type placeholderText struct {
+	get func() string
+	set func(string)
+	// runs are the rich text runs of the text, which are interpolated one by one to keep their formatting.
+	runs []placeholderText
+}
+
+// collectPlaceholderTexts collects the text parts of the sheet by the keys like header:odd, comment:A1,
+// the cells are collected by the keys like cell:A1 when withCells.
+func (x *Xlsx) collectPlaceholderTexts(sheet spreadsheet.Sheet, withCells bool) map[string]placeholderText {
+	texts := make(map[string]placeholderText)
+
+	if withCells {
+		for _, row := range sheet.Rows() {
+			for _, cell := range x.rowCells(row) {
+				c := cell
+				texts["cell:"+c.Reference()] = placeholderText{
+					get: func() string { return GetCellString(c) },
+					set: func(s string) { c.SetString(s) },
+				}
+			}
+		}
+	}
+
+	texts["name"] = placeholderText{
+		get: sheet.Name,
+		set: func(s string) { x.renameSheet(sheet, x.uniqueSheetName(s, sheet)) },
+	}
+
+	collectHeaderFooterTexts(texts, sheet.X().HeaderFooter)
+
+	// the comments part is created by Comments() when it does not exist, so the legacy drawing is checked first.
+	if sheet.X().LegacyDrawing != nil {
+		for _, c := range sheet.Comments().Comments() {
+			if rst := c.X().Text; rst != nil {
+				texts["comment:"+c.CellReference()] = rstText(rst)
+			}
+		}
+	}
+
+	if wsDr, _ := sheet.GetDrawing(); wsDr != nil {
+		for i, anchor := range wsDr.EG_Anchor {
+			if sp := anchorShape(anchor); sp != nil && sp.TxBody != nil {
+				for j, p := range sp.TxBody.P {
+					texts[fmt.Sprintf("textbox:%d:%d", i, j)] = paragraphText(p)
+				}
+			}
+		}
+	}
+
+	return texts
+}
+
+func collectHeaderFooterTexts(texts map[string]placeholderText, hf *sml.CT_HeaderFooter) {
+	if hf == nil {
+		return
+	}
+
+	for key, p := range map[string]**string{
+		"header:odd":   &hf.OddHeader,
+		"footer:odd":   &hf.OddFooter,
+		"header:even":  &hf.EvenHeader,
+		"footer:even":  &hf.EvenFooter,
+		"header:first": &hf.FirstHeader,
+		"footer:first": &hf.FirstFooter,
+	} {
+		if *p == nil {
+			continue
+		}
+
+		ptr := p
+		texts[key] = placeholderText{
+			get: func() string { return **ptr },
+			set: func(s string) { *ptr = &s },
+		}
+	}
+}
+
+// rstText joins the text of the rich text runs, the joined text is set into the first run.
+func rstText(rst *sml.CT_Rst) placeholderText {
+	runs := make([]placeholderText, len(rst.R))
+
+	for i, r := range rst.R {
+		r := r
+		runs[i] = placeholderText{get: func() string { return r.T }, set: func(s string) { r.T = s }}
+	}
+
+	return placeholderText{
+		runs: runs,
+		get: func() string {
+			if len(rst.R) == 0 {
+				if rst.T != nil {
+					return *rst.T
+				}
+
+				return ""
+			}
+
+			s := ""
+			for _, r := range rst.R {
+				s += r.T
+			}
+
+			return s
+		},
+		set: func(s string) {
+			if len(rst.R) == 0 {
+				rst.T = &s
+				return
+			}
+
+			for i, r := range rst.R {
+				if i == 0 {
+					r.T = s
+				} else {
+					r.T = ""
+				}
+			}
+		},
+	}
+}
+
+func anchorShape(anchor *spreadsheetDrawing.EG_Anchor) *spreadsheetDrawing.CT_Shape {
+	switch {
+	case anchor.TwoCellAnchor != nil && anchor.TwoCellAnchor.Choice != nil:
+		return anchor.TwoCellAnchor.Choice.Sp
+	case anchor.OneCellAnchor != nil && anchor.OneCellAnchor.Choice != nil:
+		return anchor.OneCellAnchor.Choice.Sp
+	case anchor.AbsoluteAnchor != nil && anchor.AbsoluteAnchor.Choice != nil:
+		return anchor.AbsoluteAnchor.Choice.Sp
+	default:
+		return nil
+	}
+}
+
+// paragraphText joins the text of the runs in the paragraph, the joined text is set into the first run.
+func paragraphText(p *dml.CT_TextParagraph) placeholderText {
+	runs := make([]placeholderText, 0, len(p.EG_TextRun))
+
+	for _, r := range p.EG_TextRun {
+		if r := r.R; r != nil {
+			runs = append(runs, placeholderText{get: func() string { return r.T }, set: func(s string) { r.T = s }})
+		}
+	}
+
+	return placeholderText{
+		runs: runs,
+		get: func() string {
+			s := ""
+
+			for _, r := range p.EG_TextRun {
+				if r.R != nil {
+					s += r.R.T
+				}
+			}
+
+			return s
+		},
+		set: func(s string) {
+			first := true
+
+			for _, r := range p.EG_TextRun {
+				if r.R == nil {
+					continue
+				}
+
+				if first {
+					r.R.T, first = s, false
+				} else {
+					r.R.T = ""
+				}
+			}
+		},
+	}
+}
+
+// SheetName makes the valid sheet name, the invalid characters []:*?/\ are replaced by _,
+// and the name is truncated to 31 characters.
+func SheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if runes := []rune(name); len(runes) > 31 { // nolint:gomnd
+		name = string(runes[:31])
+	}
+
+	return name
+}
+
+// placeholderParts returns the placeholders like {{Name}} in the values in order.
+func placeholderParts(pls ...PlaceholderValue) []string {
+	parts := make([]string, 0)
+
+	for _, pl := range pls {
+		for _, p := range pl.Parts {
+			if p.Var != "" {
+				parts = append(parts, p.Part)
+			}
+		}
+	}
+
+	return parts
+}
+
+// hasAllVars tells whether all the placeholders in pl have the vars,
+// so the placeholders for the other writes are kept.
+func hasAllVars(pl PlaceholderValue, vars map[string]string) bool {
+	for _, p := range pl.Parts {
+		if _, ok := vars[p.Var]; p.Var != "" && !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeWorkbookPlaceholders interpolates the placeholders in the other parts of the workbook than the cells
+// of the current sheet, like the cells of the other sheets, the headers and footers, the sheet names,
+// the comments and the text boxes, only the placeholders with all vars known are interpolated.
+func (x *Xlsx) writeWorkbookPlaceholders(vars map[string]string) {
+	for _, sheet := range x.workbook.Sheets() {
+		x.writeSheetPlaceholderTexts(sheet, vars, sheet.X() != x.currentSheet.X(), true)
+	}
+}
+
+// writeSheetPlaceholderTexts interpolates the placeholders in the text parts of the sheet,
+// the cells are interpolated when withCells, and the sheet name when withName.
+func (x *Xlsx) writeSheetPlaceholderTexts(sheet spreadsheet.Sheet, vars map[string]string, withCells, withName bool) {
+	texts := x.collectPlaceholderTexts(sheet, withCells)
+
+	for key, t := range texts {
+		if key != "name" {
+			x.interpolateText(t, vars)
+		}
+	}
+
+	if withName { // the sheet name is the last to interpolate.
+		x.interpolateText(texts["name"], vars)
+	}
+}
+
+func (x *Xlsx) interpolateText(t placeholderText, vars map[string]string) {
+	pl := x.parsePlaceholder(t.get())
+	if !(pl.HasPlaceholders() || pl.HasEscapes()) || !hasAllVars(pl, vars) {
+		return
+	}
+
+	if !x.interpolateRuns(t.runs, pl, vars) {
+		t.set(pl.Interpolate(vars))
+	}
+}
+
+// interpolateRuns interpolates the rich text runs one by one to keep their formatting,
+// and returns false when any placeholder spans the runs, which is interpolated as the joined text instead.
+func (x *Xlsx) interpolateRuns(runs []placeholderText, pl PlaceholderValue, vars map[string]string) bool {
+	if len(runs) < 2 { // nolint:gomnd
+		return false
+	}
+
+	pls := make([]PlaceholderValue, len(runs))
+	for i, r := range runs {
+		pls[i] = x.parsePlaceholder(r.get())
+	}
+
+	if strings.Join(placeholderParts(pls...), "\x00") != strings.Join(placeholderParts(pl), "\x00") {
+		return false
+	}
+
+	for i, r := range runs {
+		if pls[i].HasPlaceholders() || pls[i].HasEscapes() {
+			r.set(pls[i].Interpolate(vars))
+		}
+	}
+
+	return true
+}
+
+// readWorkbookPlaceholders reads the vars from the other parts of the workbook by the template,
+// the sheets are paired by their indexes. The parts not matching the template are skipped,
+// and the sheet names only fill the missing vars because the invalid characters are replaced.
+func (x *Xlsx) readWorkbookPlaceholders(varSet *placeholderVarSet) error {
+	if x.tmplWorkbook == nil {
+		return nil
+	}
+
+	sheets := x.workbook.Sheets()
+
+	for i, tmplSheet := range x.tmplWorkbook.Sheets() {
+		if i >= len(sheets) {
+			break
+		}
+
+		withCells := tmplSheet.X() != x.tmplSheet.X()
+		dataTexts := x.collectPlaceholderTexts(sheets[i], withCells)
+		tmplTexts := x.collectPlaceholderTexts(tmplSheet, withCells)
+		keys := make([]string, 0, len(tmplTexts))
+
+		for key := range tmplTexts {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if err := x.readPlaceholderText(varSet, tmplTexts[key], dataTexts, key, sheets[i].Name()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *Xlsx) readPlaceholderText(varSet *placeholderVarSet, t placeholderText,
+	dataTexts map[string]placeholderText, key, sheetName string,
+) error {
+	pl := x.parsePlaceholder(t.get())
+	data, ok := dataTexts[key]
+
+	if !pl.HasPlaceholders() || !ok {
+		return nil
+	}
+
+	source := fmt.Sprintf("%s of sheet %s", key, sheetName)
+
+	parsed, err := pl.Match(data.get())
+	if errors.Is(err, ErrPlaceholderMismatch) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	if key == "name" {
+		for k, v := range parsed {
+			if _, exists := varSet.vars[k]; !exists {
+				varSet.vars[k], varSet.sources[k] = v, source
+			}
+		}
+
+		return nil
+	}
+
+	return varSet.merge(parsed, source)
+}
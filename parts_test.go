@@ -0,0 +1,187 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/schema/soo/dml"
+	sd "github.com/unidoc/unioffice/schema/soo/dml/spreadsheetDrawing"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type contact struct {
+	ContactName string `asPlaceholder:"true"`
+	Company     string
+}
+
+func addTextBox(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet, text string) {
+	wsDr, _ := sheet.GetDrawing()
+	if wsDr == nil {
+		sheet.SetDrawing(wb.AddDrawing())
+		wsDr, _ = sheet.GetDrawing()
+	}
+
+	anchor := sd.NewCT_TwoCellAnchor()
+	anchor.Choice = &sd.EG_ObjectChoicesChoice{Sp: sd.NewCT_Shape()}
+	anchor.Choice.Sp.TxBody = dml.NewCT_TextBody()
+
+	p := dml.NewCT_TextParagraph()
+	p.EG_TextRun = []*dml.EG_TextRun{{R: &dml.CT_RegularTextRun{T: text}}}
+	anchor.Choice.Sp.TxBody.P = []*dml.CT_TextParagraph{p}
+
+	wsDr.EG_Anchor = append(wsDr.EG_Anchor, &sd.EG_Anchor{TwoCellAnchor: anchor})
+}
+
+func TestPlaceholderParts(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("证书-{{ContactName}}")
+	sheet.Cell("A1").SetString("联系人：{{ContactName}}")
+
+	header, footer := "&C{{Company}}", "&L联系人 {{ContactName}}"
+	sheet.X().HeaderFooter = sml.NewCT_HeaderFooter()
+	sheet.X().HeaderFooter.OddHeader = &header
+	sheet.X().HeaderFooter.OddFooter = &footer
+	sheet.Comments().AddComment("A1", "admin").AddRun().SetText("请联系{{ContactName}}")
+	addTextBox(wb, sheet, "公司：{{Company}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	c := contact{ContactName: "张三/李四", Company: "某某公司"}
+	assert.Nil(t, x.Write(c))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "证书-张三_李四", sheet.Name())
+	assert.Equal(t, "联系人：张三/李四", sheet.Cell("A1").GetString())
+	assert.Equal(t, "&C某某公司", *sheet.X().HeaderFooter.OddHeader)
+	assert.Equal(t, "&L联系人 张三/李四", *sheet.X().HeaderFooter.OddFooter)
+	assert.Equal(t, "请联系张三/李四", sheet.Comments().Comments()[0].X().Text.R[0].T)
+
+	wsDr, _ := sheet.GetDrawing()
+	assert.Equal(t, "公司：某某公司", wsDr.EG_Anchor[0].TwoCellAnchor.Choice.Sp.TxBody.P[0].EG_TextRun[0].R.T)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read contact
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, c, read)
+}
+
+func TestPlaceholderOtherSheets(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("联系人：{{ContactName}}")
+
+	other := wb.AddSheet()
+	other.SetName("附页")
+	other.Cell("B2").SetString("公司：{{Company}}")
+	other.Cell("B3").SetString("{{Unknown}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	c := contact{ContactName: "张三", Company: "某某公司"}
+	assert.Nil(t, x.Write(c))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	other = out.Sheets()[1]
+	assert.Equal(t, "公司：某某公司", other.Cell("B2").GetString())
+	assert.Equal(t, "{{Unknown}}", other.Cell("B3").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read contact
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, c, read)
+}
+
+func TestSheetName(t *testing.T) {
+	assert.Equal(t, "a_b_c_d", xlsx.SheetName("a[b]c?d"))
+	assert.Equal(t, 31, len([]rune(xlsx.SheetName("一二三四五六七八九十一二三四五六七八九十一二三四五六七八九十一二三"))))
+}
+
+func TestPlaceholderSheetRename(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("{{Company}}")
+	sheet.Cell("A1").SetString("联系人：{{ContactName}}")
+	wb.AddDefinedName("_xlnm.Print_Titles", "'{{Company}}'!$1:$1").SetLocalSheetID(0)
+
+	other := wb.AddSheet()
+	other.SetName("某某公司")
+
+	x, _ := xlsx.New(xlsx.WithTemplate(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(contact{ContactName: "张三", Company: "某某公司"}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	assert.Equal(t, "某某公司 (2)", out.Sheets()[0].Name())
+	assert.Equal(t, "'某某公司 (2)'!$1:$1", out.DefinedNames()[0].Content())
+}
+
+func TestPlaceholderRichTextRuns(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("联系人：{{ContactName}}")
+
+	rich := sheet.Comments().AddComment("A1", "admin")
+	rich.AddRun().SetText("请联系")
+	bold := rich.AddRun()
+	bold.SetBold(true)
+	bold.SetText("{{ContactName}}")
+
+	split := sheet.Comments().AddComment("B1", "admin")
+	split.AddRun().SetText("{{Contact")
+	split.AddRun().SetText("Name}}")
+
+	x, _ := xlsx.New(xlsx.WithTemplate(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(contact{ContactName: "张三", Company: "某某公司"}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	comments := out.Sheets()[0].Comments().Comments()
+	assert.Equal(t, "请联系", comments[0].X().Text.R[0].T)
+	assert.Equal(t, "张三", comments[0].X().Text.R[1].T)
+	assert.NotNil(t, comments[0].X().Text.R[1].RPr)
+	// the placeholder spanning the runs is interpolated into the first run.
+	assert.Equal(t, "张三", comments[1].X().Text.R[0].T)
+	assert.Equal(t, "", comments[1].X().Text.R[1].T)
+}
@@ -1,12 +1,34 @@
 package xlsx
 
-import "strings"
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/araddon/dateparse"
+)
 
 // PlaceholderValue represents a placeholder value.
 type PlaceholderValue struct {
 	Content string
 
 	Parts []PlaceholderPart
+
+	logger  *slog.Logger
+	filters map[string]PlaceholderFilter
+	// timeLayouts are the layouts of the time vars by the format tags, which the date filter reads by.
+	timeLayouts map[string]string
+}
+
+// log returns the logger of the Xlsx parsed the placeholders, or slog.Default().
+func (p *PlaceholderValue) log() *slog.Logger {
+	if p.logger != nil {
+		return p.logger
+	}
+
+	return slog.Default()
 }
 
 // HasPlaceholders tells that the PlaceholderValue has any placeholders.
@@ -20,108 +42,370 @@ func (p *PlaceholderValue) HasPlaceholders() bool {
 	return false
 }
 
+// HasEscapes tells that the PlaceholderValue has any escaped left delimiters like \{{,
+// which are unescaped by Interpolate.
+func (p *PlaceholderValue) HasEscapes() bool {
+	content := ""
+
+	for _, p := range p.Parts {
+		content += p.Part
+	}
+
+	return content != p.Content
+}
+
 // Interpolate interpolates placeholders with vars.
 func (p *PlaceholderValue) Interpolate(vars map[string]string) string {
 	content := ""
+	logger := p.log()
 
-	for _, p := range p.Parts {
-		if p.Var != "" {
-			content += vars[p.Var]
+	for _, part := range p.Parts {
+		if part.Var != "" {
+			content += part.format(vars[part.Var], logger, p.filters, p.timeLayouts[part.Var])
 		} else {
-			content += p.Part
+			content += part.Part
 		}
 	}
 
 	return content
 }
 
-// ParseVars parses the vars from the content.
+// ParseVars parses the vars from the content, the placeholders without the pattern hints match as few as possible,
+// the values failed to parse by the filters are kept as they are. Use Match to get the errors.
 func (p *PlaceholderValue) ParseVars(content string) (outVars map[string]string, matched bool) {
+	values, err := p.matchValues(content, false)
+	if err != nil {
+		return nil, false
+	}
+
 	outVars = make(map[string]string)
 
-	for i := 0; i < len(p.Parts); i++ {
-		v := p.Parts[i]
+	for i, v := range p.varParts() {
+		value, err := v.parse(values[i], p.filters, p.timeLayouts[v.Var])
+		if err != nil {
+			p.log().Warn("failed to parse placeholder value", "value", values[i], "var", v.Var, "error", err)
+		}
 
-		if v.Var == "" {
-			if !strings.HasPrefix(content, v.Part) {
-				return nil, false
-			}
+		outVars[v.Var] = value
+	}
 
-			content = content[len(v.Part):]
+	return outVars, true
+}
 
-			continue
+var (
+	// ErrPlaceholderMismatch defines the error of the content not matching the placeholders.
+	ErrPlaceholderMismatch = fmt.Errorf("content does not match the placeholders")
+	// ErrPlaceholderAmbiguous defines the error of the content matching the placeholders in more than one way,
+	// like {{A}}{{B}} or a literal part also appearing in the values, use the pattern hints like {{Mobile:\d+}} to fix.
+	ErrPlaceholderAmbiguous = fmt.Errorf("ambiguous placeholders")
+	// ErrPlaceholderConflict defines the error of the same var having different values.
+	ErrPlaceholderConflict = fmt.Errorf("conflict placeholder values")
+	// ErrBadPlaceholderPattern defines the error of the invalid pattern hint.
+	ErrBadPlaceholderPattern = fmt.Errorf("bad placeholder pattern")
+	// ErrUnknownPlaceholder defines the error of the template placeholder without the matching field in the strict mode.
+	ErrUnknownPlaceholder = fmt.Errorf("unknown placeholder")
+)
+
+// Match parses the vars from the content like ParseVars, but returns the errors
+// of the mismatch, the ambiguous matches, the conflict values of the same var and the filter parsing.
+func (p *PlaceholderValue) Match(content string) (map[string]string, error) {
+	values, err := p.matchValues(content, false)
+	if err != nil {
+		return nil, err
+	}
+
+	greedy, err := p.matchValues(content, true)
+	if err != nil {
+		return nil, err
+	}
+
+	outVars := make(map[string]string)
+
+	for i, v := range p.varParts() {
+		if values[i] != greedy[i] {
+			return nil, fmt.Errorf("%w: %s in %q is %q or %q", ErrPlaceholderAmbiguous, v.Var, content, values[i], greedy[i])
 		}
 
-		if i+1 >= len(p.Parts) {
-			outVars[v.Var] = content
+		value, err := v.parse(values[i], p.filters, p.timeLayouts[v.Var])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of %s: %w", values[i], v.Var, err)
+		}
 
+		if old, ok := outVars[v.Var]; ok && !SameVarValue(old, value) {
+			return nil, fmt.Errorf("%w: %s is %q and %q in %q", ErrPlaceholderConflict, v.Var, old, value, content)
+		}
+
+		outVars[v.Var] = value
+	}
+
+	return outVars, nil
+}
+
+func (p *PlaceholderValue) varParts() []PlaceholderPart {
+	parts := make([]PlaceholderPart, 0, len(p.Parts))
+
+	for _, v := range p.Parts {
+		if v.Var != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	return parts
+}
+
+// matchValues matches the content by the anchored pattern compiled from the parts,
+// and returns the raw values of the var parts in order.
+func (p *PlaceholderValue) matchValues(content string, greedy bool) ([]string, error) {
+	re, err := p.compile(greedy)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := re.FindStringSubmatch(content)
+	if sub == nil {
+		return nil, fmt.Errorf("%w: %q by %q", ErrPlaceholderMismatch, content, p.Content)
+	}
+
+	values := make([]string, 0, len(p.Parts))
+
+	for i := range p.varParts() {
+		values = append(values, sub[re.SubexpIndex(fmt.Sprintf("v%d", i))])
+	}
+
+	return values, nil
+}
+
+// compile compiles the parts into the anchored pattern like ^中国(?P<v0>.*?)人民(?P<v1>\d+)$,
+// the placeholders without the pattern hints match as many as possible when greedy, or as few as possible.
+func (p *PlaceholderValue) compile(greedy bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+
+	b.WriteString(`(?s)^`)
+
+	i := 0
+
+	for _, v := range p.Parts {
+		if v.Var == "" {
+			b.WriteString(regexp.QuoteMeta(v.Part))
 			continue
 		}
 
-		i++
-		v2 := p.Parts[i]
-		v2Pos := strings.Index(content, v2.Part)
+		pattern := ".*?"
 
-		if v2Pos < 0 {
-			return nil, false
+		switch {
+		case v.Pattern != "":
+			pattern = v.Pattern
+		case greedy:
+			pattern = ".*"
 		}
 
-		outVars[v.Var] = content[:v2Pos]
-		content = content[v2Pos+len(v2.Part):]
+		fmt.Fprintf(&b, "(?P<v%d>%s)", i, pattern)
+		i++
 	}
 
-	return outVars, true
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w in %q: %w", ErrBadPlaceholderPattern, p.Content, err)
+	}
+
+	return re, nil
+}
+
+// SameVarValue tells whether the two values of a var are the same,
+// the numbers and times in different formats like 12.5 and 12.50 are the same.
+func SameVarValue(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		fb, err := strconv.ParseFloat(b, 64)
+		return err == nil && fa == fb
+	}
+
+	ta, err := dateparse.ParseLocal(a)
+	if err != nil {
+		return false
+	}
+
+	tb, err := dateparse.ParseLocal(b)
+
+	return err == nil && ta.Equal(tb)
 }
 
 // PlaceholderPart is a placeholder sub Part after parsing.
 type PlaceholderPart struct {
 	Part string
 	Var  string
+	// Pattern is the regular expression hint of the var value for reading, like \d+ in {{Mobile:\d+}}.
+	Pattern string
+	// Pipes are the filters of the placeholder like {{Amount | money}}.
+	Pipes []PlaceholderPipe
+}
+
+// Format formats the var value by the pipes with the built-in filters.
+func (p PlaceholderPart) Format(value string) string {
+	return p.format(value, slog.Default(), nil, "")
+}
+
+// format formats the var value by the pipes with the filters, the time value in the timeLayout
+// by the format tag is converted for the date filter in the first pipe.
+func (p PlaceholderPart) format(value string, logger *slog.Logger,
+	filters map[string]PlaceholderFilter, timeLayout string,
+) string {
+	for i, pipe := range p.Pipes {
+		f, ok := lookupPlaceholderFilter(filters, pipe.Name)
+		if !ok {
+			logger.Warn("unknown placeholder filter", "filter", pipe.Name)
+			continue
+		}
+
+		if i == 0 && pipe.Name == dateFilterName {
+			value = convertTimeLayout(value, timeLayout, defaultTimeLayout)
+		}
+
+		v, err := f.Format(value, pipe.Args)
+		if err != nil {
+			logger.Warn("failed to format placeholder value", "value", value, "filter", pipe.Name, "error", err)
+			continue
+		}
+
+		value = v
+	}
+
+	return value
+}
+
+// Parse parses the formatted value back by the pipes in the reversed order with the built-in filters,
+// the filters without the Parse func keep the value as it is.
+func (p PlaceholderPart) Parse(value string) (string, error) {
+	return p.parse(value, nil, "")
 }
 
-// ParsePlaceholder parses placeholders in the content.
+// parse parses the formatted value back by the pipes with the filters, the time value parsed by the date filter
+// in the first pipe is converted into the timeLayout by the format tag.
+func (p PlaceholderPart) parse(value string, filters map[string]PlaceholderFilter, timeLayout string) (string, error) {
+	for i := len(p.Pipes) - 1; i >= 0; i-- {
+		pipe := p.Pipes[i]
+
+		f, ok := lookupPlaceholderFilter(filters, pipe.Name)
+		if !ok || f.Parse == nil {
+			continue
+		}
+
+		v, err := f.Parse(value, pipe.Args)
+		if err != nil {
+			return value, fmt.Errorf("filter %s: %w", pipe.Name, err)
+		}
+
+		value = v
+
+		if i == 0 && pipe.Name == dateFilterName {
+			value = convertTimeLayout(value, defaultTimeLayout, timeLayout)
+		}
+	}
+
+	return value, nil
+}
+
+// PlaceholderDelims defines the left and right delimiters of the placeholders, like {{ and }}.
+// The left delimiter escaped by a backslash, like \{{, is kept as the literal left delimiter.
+type PlaceholderDelims struct {
+	Left, Right string
+}
+
+// DefaultPlaceholderDelims is the default delimiters of the placeholders like {{Name}}.
+// nolint:gochecknoglobals
+var DefaultPlaceholderDelims = PlaceholderDelims{Left: "{{", Right: "}}"}
+
+// ParsePlaceholder parses placeholders in the content by the DefaultPlaceholderDelims.
 func ParsePlaceholder(content string) PlaceholderValue {
+	return DefaultPlaceholderDelims.Parse(content)
+}
+
+// Parse parses placeholders in the content by the delimiters,
+// the empty delimiters are defaulted to the ones of DefaultPlaceholderDelims.
+func (d PlaceholderDelims) Parse(content string) PlaceholderValue {
+	if d.Left == "" {
+		d.Left = DefaultPlaceholderDelims.Left
+	}
+
+	if d.Right == "" {
+		d.Right = DefaultPlaceholderDelims.Right
+	}
+
+	escaped := `\` + d.Left
 	pos := 0
+	literal := ""
 	parts := make([]PlaceholderPart, 0)
 
 	for {
 		contentPos := content[pos:]
-		lp := strings.Index(contentPos, "{{")
+		lp := strings.Index(contentPos, d.Left)
 
-		if lp < 0 {
-			if len(contentPos) > 0 {
-				parts = append(parts, PlaceholderPart{
-					Part: contentPos,
-				})
-			}
+		if lp > 0 && strings.HasPrefix(contentPos[lp-1:], escaped) {
+			literal += contentPos[:lp-1] + d.Left
+			pos += lp + len(d.Left)
 
-			break
+			continue
 		}
 
-		rp := strings.Index(content[pos+lp:], "}}")
-		if rp < 0 {
-			if len(contentPos) > 0 {
-				parts = append(parts, PlaceholderPart{
-					Part: contentPos,
-				})
+		rp := -1
+		if lp >= 0 {
+			rp = strings.Index(contentPos[lp+len(d.Left):], d.Right)
+		}
+
+		if lp < 0 || rp < 0 {
+			if literal += contentPos; literal != "" {
+				parts = append(parts, PlaceholderPart{Part: literal})
 			}
 
 			break
 		}
 
-		if lp > 0 {
-			parts = append(parts, PlaceholderPart{
-				Part: contentPos[:lp],
-			})
+		if literal += contentPos[:lp]; literal != "" {
+			parts = append(parts, PlaceholderPart{Part: literal})
+			literal = ""
 		}
 
-		pl := content[pos+lp : pos+lp+rp+2]
-		varName := strings.TrimSpace(pl[2 : len(pl)-2])
+		pl := contentPos[lp : lp+len(d.Left)+rp+len(d.Right)]
+		varName, pattern, pipes := parsePlaceholderExpr(pl[len(d.Left) : len(pl)-len(d.Right)])
 
-		parts = append(parts, PlaceholderPart{Part: pl, Var: varName})
+		parts = append(parts, PlaceholderPart{Part: pl, Var: varName, Pattern: pattern, Pipes: pipes})
 
-		pos += lp + rp + 2 // nolint:gomnd
+		pos += lp + len(pl)
 	}
 
 	return PlaceholderValue{Content: content, Parts: parts}
 }
+
+// placeholderVarSet collects the vars read from the placeholders with their sources, like cell A1,
+// to report the conflicts when the same var has different values.
+type placeholderVarSet struct {
+	vars    map[string]string
+	sources map[string]string
+}
+
+func newPlaceholderVarSet() *placeholderVarSet {
+	return &placeholderVarSet{vars: make(map[string]string), sources: make(map[string]string)}
+}
+
+// merge merges the vars read from the source, and returns ErrPlaceholderConflict
+// when a var already read has a different value.
+func (s *placeholderVarSet) merge(vars map[string]string, source string) error {
+	for k, v := range vars {
+		if old, ok := s.vars[k]; ok {
+			if !SameVarValue(old, v) {
+				return fmt.Errorf("%w: %s is %q in %s but %q in %s", ErrPlaceholderConflict, k, old, s.sources[k], v, source)
+			}
+
+			continue
+		}
+
+		s.vars[k], s.sources[k] = v, source
+	}
+
+	return nil
+}
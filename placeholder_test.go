@@ -1,10 +1,18 @@
 package xlsx_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bingoohuang/xlsx"
 	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
 )
 
 func TestParsePlaceholder(t *testing.T) {
@@ -67,3 +75,380 @@ func TestParsePlaceholder(t *testing.T) {
 	assert.False(t, ok)
 	assert.Nil(t, vars)
 }
+
+func TestPlaceholderPipes(t *testing.T) {
+	pl := xlsx.ParsePlaceholder(`{{RegisterDate | date "yyyy年MM月dd日"}} {{ Amount|money "¥" }}`)
+	assert.Equal(t, []xlsx.PlaceholderPart{
+		{
+			Part: `{{RegisterDate | date "yyyy年MM月dd日"}}`, Var: "RegisterDate",
+			Pipes: []xlsx.PlaceholderPipe{{Name: "date", Args: []string{"yyyy年MM月dd日"}}},
+		},
+		{Part: " "},
+		{
+			Part: `{{ Amount|money "¥" }}`, Var: "Amount",
+			Pipes: []xlsx.PlaceholderPipe{{Name: "money", Args: []string{"¥"}}},
+		},
+	}, pl.Parts)
+
+	content := pl.Interpolate(map[string]string{"RegisterDate": "2021-03-04 00:00:00", "Amount": "-1234567.5"})
+	assert.Equal(t, "2021年03月04日 -¥1,234,567.50", content)
+
+	vars, ok := pl.ParseVars(content)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"RegisterDate": "2021-03-04 00:00:00", "Amount": "-1234567.50"}, vars)
+
+	flags := xlsx.ParsePlaceholder(`{{Flag | yesno "是" "否"}}/{{Name | default "-"}}`)
+	assert.Equal(t, "否/-", flags.Interpolate(map[string]string{"Flag": "false"}))
+	assert.Equal(t, "是/bingoo", flags.Interpolate(map[string]string{"Flag": "true", "Name": "bingoo"}))
+}
+
+func TestPlaceholderFilters(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("{{Name | upper | default \"-\"}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithPlaceholderFilters(map[string]xlsx.PlaceholderFilter{
+		"upper": {
+			Format: func(value string, _ []string) (string, error) { return strings.ToUpper(value), nil },
+			Parse:  func(value string, _ []string) (string, error) { return strings.ToLower(value), nil },
+		},
+	}))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(person{Name: "bingoo"}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, _ := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Equal(t, "BINGOO", out.Sheets()[0].Cell("A1").GetString())
+
+	// the filters are per instance, the other instance does not know the upper filter.
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x2.Close()
+
+	assert.Nil(t, x2.Write(person{Name: "bingoo"}))
+	assert.Nil(t, x2.Save(&buf))
+
+	out, _ = spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Equal(t, "bingoo", out.Sheets()[0].Cell("A1").GetString())
+}
+
+type signedContract struct {
+	Name   string    `asPlaceholder:"true"`
+	Signed time.Time `format:"dd/MM/yyyy"`
+}
+
+func TestPlaceholderDateFilterFormatTag(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("{{Name}}")
+	sheet.Cell("A2").SetString(`签订日期：{{Signed | date "yyyy年MM月dd日"}}`)
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	c := signedContract{Name: "张三", Signed: time.Date(2021, 3, 14, 0, 0, 0, 0, time.Local)}
+	assert.Nil(t, x.Write(c))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, _ := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Equal(t, "签订日期：2021年03月14日", out.Sheets()[0].Cell("A2").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read signedContract
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, c, read)
+}
+
+func TestPlaceholderMatch(t *testing.T) {
+	adjacent := xlsx.ParsePlaceholder("{{A}}{{B}}")
+	_, err := adjacent.Match("12345")
+	assert.True(t, errors.Is(err, xlsx.ErrPlaceholderAmbiguous))
+
+	dash := xlsx.ParsePlaceholder("{{A}}-{{B}}")
+	_, err = dash.Match("x-y-z")
+	assert.True(t, errors.Is(err, xlsx.ErrPlaceholderAmbiguous))
+
+	mobileOnly := xlsx.ParsePlaceholder(`{{Name}}{{Mobile:\d+}}`)
+	assert.Equal(t, `\d+`, mobileOnly.Parts[1].Pattern)
+	assert.Equal(t, "Mobile", mobileOnly.Parts[1].Var)
+
+	_, err = mobileOnly.Match("张三13800138000")
+	assert.True(t, errors.Is(err, xlsx.ErrPlaceholderAmbiguous))
+
+	hinted := xlsx.ParsePlaceholder(`{{Name:\D+}}{{Mobile:\d+}}`)
+	vars, err := hinted.Match("张三13800138000")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Name": "张三", "Mobile": "13800138000"}, vars)
+
+	code := xlsx.ParsePlaceholder(`{{Code:"A|B"}}{{Rest}}`)
+	vars, err = code.Match("Bxyz")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Code": "B", "Rest": "xyz"}, vars)
+
+	mobile := xlsx.ParsePlaceholder("{{Mobile:\\d+}}")
+	_, err = mobile.Match("abc")
+	assert.True(t, errors.Is(err, xlsx.ErrPlaceholderMismatch))
+
+	bad := xlsx.ParsePlaceholder("{{A:(}}")
+	_, err = bad.Match("abc")
+	assert.True(t, errors.Is(err, xlsx.ErrBadPlaceholderPattern))
+
+	twice := xlsx.ParsePlaceholder("{{A}}/{{A}}")
+	_, err = twice.Match("1/2")
+	assert.True(t, errors.Is(err, xlsx.ErrPlaceholderConflict))
+
+	money := xlsx.ParsePlaceholder(`{{Amount | money}}`)
+	_, err = money.Match("abc")
+	assert.NotNil(t, err)
+}
+
+type person struct {
+	Name string `asPlaceholder:"true"`
+}
+
+func TestPlaceholderConflictCells(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("{{Name}}")
+	sheet.Cell("A2").SetString("签名：{{Name}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	wb, _ = spreadsheet.Read(bytes.NewReader(tmpl), int64(len(tmpl)))
+	wb.Sheets()[0].Cell("A1").SetString("张三")
+	wb.Sheets()[0].Cell("A2").SetString("签名：李四")
+
+	data := saveWorkbook(t, wb)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(data))
+	defer x2.Close()
+
+	var read person
+
+	err := x2.Read(&read)
+	assert.True(t, errors.Is(err, xlsx.ErrPlaceholderConflict))
+	assert.Contains(t, err.Error(), "cell A1")
+	assert.Contains(t, err.Error(), "cell A2")
+}
+
+type owner struct {
+	Name string
+}
+
+type certificate struct {
+	Owner        owner `asPlaceholder:"true"`
+	Holder       *owner
+	RegisterDate time.Time
+	Flag         bool
+}
+
+func TestPlaceholderNestedPaths(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("所有人：{{Owner.Name}}")
+	sheet.Cell("A2").SetString("持有人：{{Holder.Name | default \"无\"}}")
+	sheet.Cell("A3").SetString(`登记日期：{{RegisterDate | date "yyyy年MM月dd日"}}`)
+	sheet.Cell("A4").SetString(`有效：{{Flag | yesno "是" "否"}}`)
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	c := certificate{
+		Owner:        owner{Name: "张三"},
+		RegisterDate: time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local),
+		Flag:         true,
+	}
+
+	assert.Nil(t, x.Write(c))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, _ := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "所有人：张三", sheet.Cell("A1").GetString())
+	assert.Equal(t, "持有人：无", sheet.Cell("A2").GetString())
+	assert.Equal(t, "登记日期：2021年03月04日", sheet.Cell("A3").GetString())
+	assert.Equal(t, "有效：是", sheet.Cell("A4").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read certificate
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, c, read)
+}
+
+type invoice struct {
+	Amount       float64 `asPlaceholder:"true"`
+	Paid         bool
+	RegisterDate time.Time `format:"yyyy-MM-dd"`
+}
+
+func TestTypedPlaceholders(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("{{Amount}}")
+	sheet.Cell("B1").SetFormulaRaw("A1*2")
+	sheet.Cell("A2").SetString("{{Paid}}")
+	sheet.Cell("A3").SetString("{{RegisterDate}}")
+	sheet.Cell("A4").SetString("金额：{{Amount}}")
+	sheet.Cell("A5").SetString("{{RegisterDate}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	inv := invoice{Amount: 12.5, Paid: true, RegisterDate: time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local)}
+	assert.Nil(t, x.Write(inv))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, _ := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	sheet = out.Sheets()[0]
+
+	assert.True(t, sheet.Cell("A1").IsNumber())
+	assert.True(t, sheet.Cell("A2").IsBool())
+	assert.True(t, sheet.Cell("A3").IsNumber())
+	assert.Equal(t, "2021-03-04", sheet.Cell("A3").GetFormattedValue())
+	assert.Equal(t, "金额：12.5", sheet.Cell("A4").GetString())
+	assert.Equal(t, *sheet.Cell("A3").X().SAttr, *sheet.Cell("A5").X().SAttr)
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read invoice
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, inv, read)
+}
+
+// cents is a value struct written by the text, like decimal.Decimal.
+type cents struct {
+	Value int64
+}
+
+func (c cents) String() string { return fmt.Sprintf("%d.%02d", c.Value/100, c.Value%100) }
+
+func (c *cents) UnmarshalText(text []byte) error {
+	f, err := strconv.ParseFloat(string(text), 64)
+	c.Value = int64(math.Round(f * 100))
+
+	return err
+}
+
+type payment struct {
+	Amount cents `asPlaceholder:"true"`
+	Fee    *cents
+}
+
+func TestPlaceholderStringerField(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("a:{{Amount}}")
+	sheet.Cell("A2").SetString("分：{{Amount.Value}}")
+	sheet.Cell("A3").SetString("手续费：{{Fee}}")
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	p := payment{Amount: cents{Value: 1234}, Fee: &cents{Value: 5}}
+	assert.Nil(t, x.Write(p))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, _ := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "a:12.34", sheet.Cell("A1").GetString())
+	assert.Equal(t, "分：1234", sheet.Cell("A2").GetString())
+	assert.Equal(t, "手续费：0.05", sheet.Cell("A3").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read payment
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, p, read)
+}
+
+type treeNode struct {
+	Name   string `asPlaceholder:"true"`
+	Parent *treeNode
+}
+
+func TestRecursivePlaceholderBean(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("节点：{{Name}}")
+	sheet.Cell("A2").SetString("上级：{{Parent.Name}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x.Close()
+
+	n := treeNode{Name: "研发部", Parent: &treeNode{Name: "总部"}}
+	assert.Nil(t, x.Write(n))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, _ := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "节点：研发部", sheet.Cell("A1").GetString())
+	assert.Equal(t, "上级：总部", sheet.Cell("A2").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read treeNode
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, n, read)
+}
+
+func TestPlaceholderDelims(t *testing.T) {
+	d := xlsx.PlaceholderDelims{Left: "${", Right: "}"}
+
+	pl := d.Parse(`{"name": "${Name}", "age": ${Age}}`)
+	assert.Equal(t, `{"name": "张三", "age": 18}`, pl.Interpolate(map[string]string{"Name": "张三", "Age": "18"}))
+
+	pl = d.Parse(`\${Name} is ${Name}`)
+	assert.True(t, pl.HasEscapes())
+	assert.Equal(t, `${Name} is 张三`, pl.Interpolate(map[string]string{"Name": "张三"}))
+
+	vars, err := pl.Match(`${Name} is 李四`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Name": "李四"}, vars)
+
+	pl = xlsx.ParsePlaceholder(`\{{"a": {"b": 1}} {{Name}}`)
+	assert.Equal(t, `{{"a": {"b": 1}} 张三`, pl.Interpolate(map[string]string{"Name": "张三"}))
+
+	pl = xlsx.ParsePlaceholder("{{Name}}")
+	assert.False(t, pl.HasEscapes())
+}
@@ -0,0 +1,175 @@
+package xlsx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// PrintSetup defines the print setup of the written sheet.
+type PrintSetup struct {
+	// RepeatTitle repeats the rows until the title row on each printed page.
+	RepeatTitle bool
+	Landscape   bool
+	// FitToWidth fits all the columns into one page width.
+	FitToWidth bool
+	// Header and Footer are the page header and footer texts,
+	// which can contain the codes like &P for the page number and &N for the number of pages.
+	Header, Footer string
+}
+
+// WithFreezeTitle freezes the panes at the title row, so the title row(s) keep visible when scrolling.
+func WithFreezeTitle() WriteOptionFn {
+	return func(o *WriteOption) {
+		o.FreezeTitle = true
+	}
+}
+
+// WithAutoFilter enables the autofilter over the written range from the title row.
+func WithAutoFilter() WriteOptionFn {
+	return func(o *WriteOption) {
+		o.AutoFilter = true
+	}
+}
+
+// WithPrintSetup defines the print setup of the written sheet.
+func WithPrintSetup(v PrintSetup) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.PrintSetup = &v
+	}
+}
+
+// writtenRange is the range of the written title and data rows.
+type writtenRange struct {
+	titledRowNum      uint32 // 0 when there is no title row
+	lastRowNum        uint32
+	firstCol, lastCol string
+}
+
+func titledWrittenRange(l templateLocation, rowsWritten uint32) writtenRange {
+	minIdx, maxIdx := uint32(0), uint32(0)
+
+	for i, tf := range l.titleFields {
+		idx := reference.ColumnToIndex(tf.Column)
+		if i == 0 || idx < minIdx {
+			minIdx = idx
+		}
+
+		if i == 0 || idx > maxIdx {
+			maxIdx = idx
+		}
+	}
+
+	return writtenRange{
+		titledRowNum: l.titledRowNum,
+		lastRowNum:   l.titledRowNum + rowsWritten,
+		firstCol:     reference.IndexToColumn(minIdx),
+		lastCol:      reference.IndexToColumn(maxIdx),
+	}
+}
+
+func (x *Xlsx) setupSheet(w writtenRange, option WriteOption) {
+	if w.titledRowNum == 0 {
+		x.setupPrint(w, option.PrintSetup)
+		return
+	}
+
+	if option.FreezeTitle {
+		x.freezeRows(w.titledRowNum)
+	}
+
+	if option.AutoFilter {
+		x.currentSheet.SetAutoFilter(fmt.Sprintf("%s%d:%s%d", w.firstCol, w.titledRowNum, w.lastCol, w.lastRowNum))
+	}
+
+	x.setupPrint(w, option.PrintSetup)
+}
+
+func (x *Xlsx) freezeRows(rows uint32) {
+	ySplit := float64(rows)
+	topLeft := fmt.Sprintf("A%d", rows+1)
+
+	x.currentSheet.InitialView().X().Pane = &sml.CT_Pane{
+		YSplitAttr:      &ySplit,
+		TopLeftCellAttr: &topLeft,
+		ActivePaneAttr:  sml.ST_PaneBottomLeft,
+		StateAttr:       sml.ST_PaneStateFrozen,
+	}
+}
+
+func (x *Xlsx) setupPrint(w writtenRange, p *PrintSetup) {
+	if p == nil {
+		return
+	}
+
+	ws := x.currentSheet.X()
+
+	if p.Landscape || p.FitToWidth {
+		if ws.PageSetup == nil {
+			ws.PageSetup = sml.NewCT_PageSetup()
+		}
+	}
+
+	if p.Landscape {
+		ws.PageSetup.OrientationAttr = sml.ST_OrientationLandscape
+	}
+
+	if p.FitToWidth {
+		one, zero := uint32(1), uint32(0)
+		ws.PageSetup.FitToWidthAttr = &one
+		ws.PageSetup.FitToHeightAttr = &zero
+
+		if ws.SheetPr == nil {
+			ws.SheetPr = sml.NewCT_SheetPr()
+		}
+
+		fitToPage := true
+		ws.SheetPr.PageSetUpPr = &sml.CT_PageSetUpPr{FitToPageAttr: &fitToPage}
+	}
+
+	if p.Header != "" || p.Footer != "" {
+		if ws.HeaderFooter == nil {
+			ws.HeaderFooter = sml.NewCT_HeaderFooter()
+		}
+
+		if p.Header != "" {
+			ws.HeaderFooter.OddHeader = &p.Header
+		}
+
+		if p.Footer != "" {
+			ws.HeaderFooter.OddFooter = &p.Footer
+		}
+	}
+
+	if p.RepeatTitle && w.titledRowNum > 0 {
+		x.setPrintTitles(w.titledRowNum)
+	}
+}
+
+const printTitlesName = "_xlnm.Print_Titles"
+
+func (x *Xlsx) setPrintTitles(titledRowNum uint32) {
+	sheetIndex := x.sheetIndex()
+	content := fmt.Sprintf("'%s'!$1:$%d", strings.ReplaceAll(x.currentSheet.Name(), "'", "''"), titledRowNum)
+
+	for _, dn := range x.workbook.DefinedNames() {
+		if id := dn.X().LocalSheetIdAttr; dn.Name() == printTitlesName && id != nil && *id == sheetIndex {
+			dn.SetContent(content)
+			return
+		}
+	}
+
+	x.workbook.AddDefinedName(printTitlesName, content).SetLocalSheetID(sheetIndex)
+}
+
+func (x *Xlsx) sheetIndex() uint32 {
+	for i, sh := range x.workbook.Sheets() {
+		if sh.X() == x.currentSheet.X() {
+			return uint32(i)
+		}
+	}
+
+	return 0
+}
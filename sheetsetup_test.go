@@ -0,0 +1,94 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestSheetSetup(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]memberStat{
+		{Total: 100, New: 50, Effective: 50},
+		{Total: 200, New: 60, Effective: 140},
+	}, xlsx.WithFreezeTitle(), xlsx.WithAutoFilter(), xlsx.WithPrintSetup(xlsx.PrintSetup{
+		RepeatTitle: true,
+		Landscape:   true,
+		FitToWidth:  true,
+		Header:      "&C会员统计",
+		Footer:      "&C第 &P 页，共 &N 页",
+	}))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	ws := wb.Sheets()[0].X()
+	pane := ws.SheetViews.SheetView[0].Pane
+	assert.Equal(t, 1.0, *pane.YSplitAttr)
+	assert.Equal(t, "A2", *pane.TopLeftCellAttr)
+	assert.Equal(t, sml.ST_PaneStateFrozen, pane.StateAttr)
+	assert.Equal(t, "A1:C3", *ws.AutoFilter.RefAttr)
+	assert.Equal(t, sml.ST_OrientationLandscape, ws.PageSetup.OrientationAttr)
+	assert.Equal(t, uint32(1), *ws.PageSetup.FitToWidthAttr)
+	assert.Equal(t, "&C会员统计", *ws.HeaderFooter.OddHeader)
+
+	found := false
+
+	for _, dn := range wb.DefinedNames() {
+		if dn.Name() == "_xlnm.Print_Titles" {
+			found = true
+			assert.Equal(t, "'会员'!$1:$1", dn.Content())
+		}
+	}
+
+	assert.True(t, found)
+}
+
+func TestSheetSetupTemplate(t *testing.T) {
+	x, _ := xlsx.New(xlsx.WithTemplate("testdata/template.xlsx"))
+	defer x.Close()
+
+	err := x.Write([]memberStat{
+		{Total: 100, New: 50, Effective: 50},
+		{Total: 200, New: 60, Effective: 140},
+	}, xlsx.WithFreezeTitle(), xlsx.WithAutoFilter(), xlsx.WithPrintSetup(xlsx.PrintSetup{RepeatTitle: true}))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet, err := wb.GetSheet("会员")
+	assert.Nil(t, err)
+
+	ws := sheet.X()
+	pane := ws.SheetViews.SheetView[0].Pane
+	assert.Equal(t, 1.0, *pane.YSplitAttr)
+	assert.Equal(t, "A2", *pane.TopLeftCellAttr)
+	assert.Equal(t, sml.ST_PaneStateFrozen, pane.StateAttr)
+	assert.Equal(t, "A1:C3", *ws.AutoFilter.RefAttr)
+
+	titles := make([]string, 0)
+
+	for _, dn := range wb.DefinedNames() {
+		if dn.Name() == "_xlnm.Print_Titles" {
+			titles = append(titles, dn.Content())
+		}
+	}
+
+	assert.Equal(t, []string{"'会员'!$1:$1"}, titles)
+}
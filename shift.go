@@ -0,0 +1,336 @@
+package xlsx
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// cellRow returns the row number after the rows are shifted, and false when the row is removed.
+// The n rows inserted after the row afterRow shift the rows below down,
+// and the -n rows removed ending at the row afterRow shift the rows below up.
+func (s rowShift) cellRow(rowNum uint32) (uint32, bool) {
+	switch {
+	case rowNum > s.afterRow:
+		return uint32(int(rowNum) + s.n), true
+	case s.n < 0 && int(rowNum) > int(s.afterRow)+s.n:
+		return 0, false
+	default:
+		return rowNum, true
+	}
+}
+
+// rangeRows returns the rows of the range from the row from to the row to after the rows are shifted,
+// the range ending at the row afterRow is expanded over the inserted rows like SUM(B3:B3) of a loop row,
+// the range is shrunk by the removed rows, and false is returned when all rows of the range are removed.
+func (s rowShift) rangeRows(from, to uint32) (uint32, uint32, bool) {
+	if s.n >= 0 {
+		if from > s.afterRow {
+			from += uint32(s.n)
+		}
+
+		if to >= s.afterRow {
+			to += uint32(s.n)
+		}
+
+		return from, to, true
+	}
+
+	n := uint32(-s.n)
+	fromRow := s.afterRow - n + 1
+	from = shiftRowUp(from, s.afterRow, n, fromRow)
+	to = shiftRowUp(to+1, s.afterRow, n, fromRow) - 1
+
+	return from, to, to >= from
+}
+
+// refPattern matches the cell references like A1, $A$1, Sheet1!A1:B2 and 'My Sheet'!$1:$3 in the formulas.
+var refPattern = regexp.MustCompile(`(?:('(?:[^']|'')+'|[\p{L}_][\p{L}\p{N}_.]*)!)?` +
+	`(?:(\$?[A-Za-z]{1,3}\$?[0-9]+)(?::(\$?[A-Za-z]{1,3}\$?[0-9]+))?|(\$?[0-9]+):(\$?[0-9]+))`)
+
+// formula shifts the row numbers of the references in the formula to the sheet with the name,
+// the references without the sheet prefix are shifted when the formula is local to the sheet.
+// The references to the removed rows are replaced by #REF! like excel does.
+func (s rowShift) formula(f, sheetName string, local bool) string {
+	var sb strings.Builder
+
+	// the string literals in the double quotes are kept as they are.
+	for i, part := range strings.Split(f, `"`) {
+		if i > 0 {
+			sb.WriteString(`"`)
+		}
+
+		if i%2 == 1 {
+			sb.WriteString(part)
+			continue
+		}
+
+		sb.WriteString(s.formulaPart(part, sheetName, local))
+	}
+
+	return sb.String()
+}
+
+func (s rowShift) formulaPart(f, sheetName string, local bool) string {
+	var sb strings.Builder
+
+	last := 0
+
+	for _, m := range refPattern.FindAllStringSubmatchIndex(f, -1) {
+		if !isRefBoundary(f, m[0], m[1]) {
+			continue
+		}
+
+		refStart := m[0]
+
+		if m[2] >= 0 {
+			if !strings.EqualFold(unquoteSheetName(f[m[2]:m[3]]), sheetName) {
+				continue
+			}
+
+			refStart = m[3] + 1 // after the !
+		} else if !local {
+			continue
+		}
+
+		sb.WriteString(f[last:refStart])
+		sb.WriteString(s.ref(f[refStart:m[1]]))
+
+		last = m[1]
+	}
+
+	sb.WriteString(f[last:])
+
+	return sb.String()
+}
+
+// ref shifts the reference without the sheet prefix, like A1, A1:B2 or 1:3.
+func (s rowShift) ref(ref string) string {
+	parts := strings.Split(ref, ":")
+
+	if len(parts) == 1 {
+		row, ok := s.cellRow(tokenRow(parts[0]))
+		if !ok {
+			return "#REF!"
+		}
+
+		return withTokenRow(parts[0], row)
+	}
+
+	from, to, ok := s.rangeRows(tokenRow(parts[0]), tokenRow(parts[1]))
+	if !ok {
+		return "#REF!"
+	}
+
+	return withTokenRow(parts[0], from) + ":" + withTokenRow(parts[1], to)
+}
+
+// sqref shifts the references of the sqref like the ranges of the data validations,
+// the references of the removed rows are dropped.
+func (s rowShift) sqref(refs []string) []string {
+	shifted := make([]string, 0, len(refs))
+
+	for _, ref := range refs {
+		for _, r := range strings.Fields(ref) {
+			if v := s.ref(r); v != "#REF!" {
+				shifted = append(shifted, v)
+			}
+		}
+	}
+
+	return shifted
+}
+
+// isRefBoundary tells the match is a whole reference, instead of a part of a name or a function like LOG10(.
+func isRefBoundary(f string, start, end int) bool {
+	if start > 0 {
+		if c := f[start-1]; isNameChar(c) || c == '$' || c == '!' {
+			return false
+		}
+	}
+
+	if end < len(f) {
+		if c := f[end]; isNameChar(c) || c == '(' || c == '!' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+func unquoteSheetName(name string) string {
+	if strings.HasPrefix(name, "'") && strings.HasSuffix(name, "'") && len(name) > 1 {
+		return strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+	}
+
+	return name
+}
+
+// tokenRow returns the row number of the reference token like $A$12 or $12.
+func tokenRow(token string) uint32 {
+	row, _ := strconv.ParseUint(token[rowDigitsIndex(token):], 10, 32)
+
+	return uint32(row)
+}
+
+// withTokenRow replaces the row number of the reference token, the $ markers are kept.
+func withTokenRow(token string, row uint32) string {
+	return token[:rowDigitsIndex(token)] + strconv.FormatUint(uint64(row), 10)
+}
+
+func rowDigitsIndex(token string) int {
+	i := len(token)
+	for i > 0 && token[i-1] >= '0' && token[i-1] <= '9' {
+		i--
+	}
+
+	return i
+}
+
+// shiftReferences shifts the references to the rows of the sheet after the rows are inserted or removed,
+// which are the formulas in all sheets, the data validations, the conditional formats
+// and the auto filter of the sheet, and the defined names like Print_Titles and Print_Area.
+func shiftReferences(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet, s rowShift) {
+	name := sheet.Name()
+
+	for _, sh := range wb.Sheets() {
+		local := sh.X() == sheet.X()
+
+		for _, row := range sh.X().SheetData.Row {
+			for _, c := range row.C {
+				if c.F == nil {
+					continue
+				}
+
+				c.F.Content = s.formula(c.F.Content, name, local)
+
+				if local && c.F.RefAttr != nil {
+					ref := s.ref(*c.F.RefAttr)
+					c.F.RefAttr = &ref
+				}
+			}
+		}
+	}
+
+	ws := sheet.X()
+	shiftDataValidations(ws, s, name)
+	shiftConditionalFormats(ws, s, name)
+
+	if af := ws.AutoFilter; af != nil && af.RefAttr != nil {
+		if ref := s.ref(*af.RefAttr); ref != "#REF!" {
+			af.RefAttr = &ref
+		} else {
+			ws.AutoFilter = nil
+		}
+	}
+
+	if dns := wb.X().DefinedNames; dns != nil {
+		for _, dn := range dns.DefinedName {
+			dn.Content = s.formula(dn.Content, name, false)
+		}
+	}
+}
+
+func shiftDataValidations(ws *sml.Worksheet, s rowShift, sheetName string) {
+	dvs := ws.DataValidations
+	if dvs == nil {
+		return
+	}
+
+	kept := dvs.DataValidation[:0]
+
+	for _, dv := range dvs.DataValidation {
+		if dv.SqrefAttr = s.sqref(dv.SqrefAttr); len(dv.SqrefAttr) == 0 {
+			continue
+		}
+
+		for _, f := range []*string{dv.Formula1, dv.Formula2} {
+			if f != nil {
+				*f = s.formula(*f, sheetName, true)
+			}
+		}
+
+		kept = append(kept, dv)
+	}
+
+	dvs.DataValidation = kept
+
+	if len(kept) == 0 {
+		ws.DataValidations = nil
+	} else {
+		n := uint32(len(kept))
+		dvs.CountAttr = &n
+	}
+}
+
+func shiftConditionalFormats(ws *sml.Worksheet, s rowShift, sheetName string) {
+	kept := ws.ConditionalFormatting[:0]
+
+	for _, cf := range ws.ConditionalFormatting {
+		if cf.SqrefAttr != nil {
+			sqref := sml.ST_Sqref(s.sqref(*cf.SqrefAttr))
+			if len(sqref) == 0 {
+				continue
+			}
+
+			cf.SqrefAttr = &sqref
+		}
+
+		for _, r := range cf.CfRule {
+			for i, f := range r.Formula {
+				r.Formula[i] = s.formula(f, sheetName, true)
+			}
+		}
+
+		kept = append(kept, cf)
+	}
+
+	ws.ConditionalFormatting = kept
+}
+
+// shiftDrawing shifts the images and shapes anchored to the shifted rows,
+// the ones anchored inside the removed rows are removed.
+func shiftDrawing(sheet spreadsheet.Sheet, s rowShift) {
+	wsDr, _ := sheet.GetDrawing()
+	if wsDr == nil {
+		return
+	}
+
+	anchors := wsDr.EG_Anchor[:0]
+
+	for _, anchor := range wsDr.EG_Anchor {
+		from, to := anchorMarkers(anchor)
+		if from == nil {
+			anchors = append(anchors, anchor)
+			continue
+		}
+
+		// the markers are zero based.
+		fromRow, ok := s.cellRow(uint32(from.Row + 1))
+		if !ok {
+			continue
+		}
+
+		from.Row = int32(fromRow) - 1
+
+		if to != nil {
+			toRow, ok := s.cellRow(uint32(to.Row + 1))
+			if !ok { // the shape ends inside the removed rows, which is cut at the first row below.
+				toRow = uint32(int(s.afterRow) + s.n + 1)
+			}
+
+			to.Row = int32(toRow) - 1
+		}
+
+		anchors = append(anchors, anchor)
+	}
+
+	wsDr.EG_Anchor = anchors
+}
@@ -0,0 +1,115 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// checkPlaceholders checks the placeholders of the template sheet in the strict mode,
+// and returns ErrUnknownPlaceholder listing the placeholders without the matching fields,
+// like {{Nmae}} at cell A1. The loop element placeholders like {{.Name}} are checked by the slice element fields.
+func (x *Xlsx) checkPlaceholders(fields []reflect.StructField) error {
+	if !x.option.StrictPlaceholders {
+		return nil
+	}
+
+	known := make(map[string]reflect.StructField)
+	placeholderFieldTypes(known, "", fields)
+
+	unknown := make([]string, 0)
+	addUnknown := func(pl PlaceholderValue, source string, elemKnown map[string]reflect.StructField) {
+		for _, p := range pl.Parts {
+			if name, _, ok := parseIfVar(p.Var); ok {
+				if !hasFieldPath(known, name) {
+					unknown = append(unknown, p.Part+" at "+source)
+				}
+
+				continue
+			}
+
+			if isRowMarker(p.Var) {
+				continue
+			}
+
+			if _, ok := known[p.Var]; ok {
+				continue
+			}
+
+			if _, ok := elemKnown[p.Var]; !ok && p.Var != "" {
+				unknown = append(unknown, p.Part+" at "+source)
+			}
+		}
+	}
+
+	loops := x.collectPlaceholderLoops(x.tmplSheet)
+
+	for _, l := range loops {
+		f, ok := findPlaceholderField(fields, l.Name)
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("range .%s at row %d", l.Name, l.RowNum))
+		}
+
+		elemKnown := elemFieldTypes(f.Type)
+
+		for _, c := range l.Cells {
+			addUnknown(c.PlaceholderValue, fmt.Sprintf("cell %s%d", c.Column, l.RowNum), elemKnown)
+		}
+	}
+
+	for ref, pl := range x.collectPlaceholders(x.tmplSheet) {
+		if r, err := reference.ParseCellReference(ref); err != nil || !isLoopRow(loops, r.RowIdx) {
+			addUnknown(pl, "cell "+ref, nil)
+		}
+	}
+
+	for key, t := range x.collectPlaceholderTexts(x.tmplSheet, false) {
+		addUnknown(x.parsePlaceholder(t.get()), key, nil)
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+
+	return fmt.Errorf("%w: %s", ErrUnknownPlaceholder, strings.Join(unknown, ", "))
+}
+
+// elemFieldTypes collects the fields of the slice element by the var names like .Name,
+// or . for the non-struct element.
+func elemFieldTypes(t reflect.Type) map[string]reflect.StructField {
+	fieldTypes := make(map[string]reflect.StructField)
+
+	if t == nil || t.Kind() != reflect.Slice {
+		return fieldTypes
+	}
+
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	if elem.Kind() != reflect.Struct {
+		fieldTypes["."] = reflect.StructField{Type: elem}
+		return fieldTypes
+	}
+
+	placeholderFieldTypes(fieldTypes, ".", exportedFields(elem))
+
+	return fieldTypes
+}
+
+// hasFieldPath tells the name is a field, or a nested struct field with the fields like Owner.Name.
+func hasFieldPath(known map[string]reflect.StructField, name string) bool {
+	for k := range known {
+		if k == name || strings.HasPrefix(k, name+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestPlaceholderCustomDelims(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("订单：${OrderNo}")
+	sheet.Cell("A2").SetString(`{"order": "{{OrderNo}}"}`)
+	sheet.Cell("A3").SetString("${range .Items}${.Name}")
+	sheet.Cell("B3").SetString("${.Qty}${end}")
+	sheet.Cell("A4").SetString(`合计：${Total} \${Total}`)
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithPlaceholderDelims("${", "}"))
+	defer x.Close()
+
+	order := orderForm{OrderNo: "D001", Items: []formItem{{Name: "苹果", Qty: 3}, {Name: "梨", Qty: 5}}, Total: 8}
+	assert.Nil(t, x.Write(order))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	out, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = out.Sheets()[0]
+	assert.Equal(t, "订单：D001", sheet.Cell("A1").GetString())
+	assert.Equal(t, `{"order": "{{OrderNo}}"}`, sheet.Cell("A2").GetString())
+	assert.Equal(t, "苹果", sheet.Cell("A3").GetString())
+	assert.Equal(t, "梨", sheet.Cell("A4").GetString())
+	assert.Equal(t, "合计：8 ${Total}", sheet.Cell("A5").GetString())
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithExcel(buf.Bytes()),
+		xlsx.WithPlaceholderDelims("${", "}"))
+	defer x2.Close()
+
+	var read orderForm
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, order, read)
+}
+
+func TestStrictPlaceholders(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("订单：{{OrderNo}} {{Customer}}")
+	sheet.Cell("A2").SetString("{{range .Items}}{{.Name}}")
+	sheet.Cell("B2").SetString("{{.Price}}{{end}}")
+	sheet.Cell("A3").SetString(`合计：{{Total}} \{{Total}}`)
+	sheet.Cell("A4").SetString("{{if .Paid}}已付{{end}}")
+
+	tmpl := saveWorkbook(t, wb)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(tmpl), xlsx.WithStrictPlaceholders())
+	defer x.Close()
+
+	err := x.Write(orderForm{OrderNo: "D001"})
+	assert.True(t, errors.Is(err, xlsx.ErrUnknownPlaceholder))
+	assert.Contains(t, err.Error(), "{{Customer}} at cell A1")
+	assert.Contains(t, err.Error(), "{{.Price}} at cell B2")
+	assert.Contains(t, err.Error(), "{{if .Paid}} at cell A4")
+	assert.NotContains(t, err.Error(), "OrderNo")
+	assert.NotContains(t, err.Error(), "Total")
+
+	x2, _ := xlsx.New(xlsx.WithTemplate(tmpl))
+	defer x2.Close()
+
+	assert.Nil(t, x2.Write(orderForm{OrderNo: "D001"}))
+}
@@ -0,0 +1,243 @@
+package xlsx
+
+import (
+	"strings"
+
+	"github.com/unidoc/unioffice/color"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// Style defines a cell style which can be applied to the title row or
+// registered by name and referenced by the style tag of a field, like `style:"money"`.
+type Style struct {
+	Bold, Italic bool
+	FontName     string
+	FontSize     float64
+	FontColor    string // hex color like FF0000
+	Fill         string // hex background color like FFFF00
+
+	Border      bool   // thin border around the cell
+	BorderColor string // hex color, default black
+
+	Horizontal string // left, center, right
+	Vertical   string // top, center, bottom
+	Wrap       bool
+
+	NumberFormat string // like #,##0.00 or yyyy-mm-dd
+}
+
+// IsEmpty tells that the style has nothing set.
+func (s Style) IsEmpty() bool { return s == Style{} }
+
+// WithStyles defines the named styles which can be referenced by the style tag.
+func WithStyles(v map[string]Style) OptionFn {
+	return func(o *Option) { o.Styles = v }
+}
+
+// RegisterStyle registers a named style which can be referenced by the style tag.
+func (x *Xlsx) RegisterStyle(name string, s Style) {
+	if x.option.Styles == nil {
+		x.option.Styles = make(map[string]Style)
+	}
+
+	x.option.Styles[name] = s
+}
+
+// WithTitleStyle defines the style of the generated title row.
+func WithTitleStyle(v Style) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.TitleStyle = v
+	}
+}
+
+// lookupStyle finds the named style for the style tag.
+func (x *Xlsx) lookupStyle(name string) (Style, bool) {
+	if name == "" {
+		return Style{}, false
+	}
+
+	s, ok := x.option.Styles[name]
+
+	return s, ok
+}
+
+// setCellStyle sets the style to the cell, the stylesheet entries are reused for the same style.
+func (x *Xlsx) setCellStyle(cell spreadsheet.Cell, s Style) {
+	if s.IsEmpty() {
+		return
+	}
+
+	if x.styles == nil {
+		x.styles = make(map[Style]uint32)
+	}
+
+	idx, ok := x.styles[s]
+	if !ok {
+		idx = x.createCellStyle(s).Index()
+		x.styles[s] = idx
+	}
+
+	cell.SetStyleIndex(idx)
+}
+
+// mergedStyleKey is the key of the stylesheet entries merged by mergeCellStyle.
+type mergedStyleKey struct {
+	base  uint32
+	style Style
+}
+
+// mergeCellStyle sets the style over the existing style of the cell, like the style copied from the template row,
+// the font and border are derived from the existing ones, and the alignment and number format are kept unless set.
+func (x *Xlsx) mergeCellStyle(cell spreadsheet.Cell, s Style) {
+	if s.IsEmpty() {
+		return
+	}
+
+	base := cell.X().SAttr
+	if base == nil || *base == 0 || int(*base) >= len(x.workbook.StyleSheet.X().CellXfs.Xf) {
+		x.setCellStyle(cell, s)
+		return
+	}
+
+	if x.mergedStyles == nil {
+		x.mergedStyles = make(map[mergedStyleKey]uint32)
+	}
+
+	key := mergedStyleKey{base: *base, style: s}
+
+	idx, ok := x.mergedStyles[key]
+	if !ok {
+		idx = x.createMergedCellStyle(*base, s).Index()
+		x.mergedStyles[key] = idx
+	}
+
+	cell.SetStyleIndex(idx)
+}
+
+func (x *Xlsx) createCellStyle(s Style) spreadsheet.CellStyle {
+	cs := x.workbook.StyleSheet.AddCellStyle()
+	x.applyStyle(cs, s)
+
+	return cs
+}
+
+// createMergedCellStyle creates a copy of the base cell style with the style applied over it.
+func (x *Xlsx) createMergedCellStyle(base uint32, s Style) spreadsheet.CellStyle {
+	xfs := x.workbook.StyleSheet.X().CellXfs
+	xf := *xfs.Xf[base]
+
+	if xf.Alignment != nil {
+		alignment := *xf.Alignment
+		xf.Alignment = &alignment
+	}
+
+	cs := x.workbook.StyleSheet.AddCellStyle()
+	*xfs.Xf[cs.Index()] = xf
+	x.applyStyle(cs, s)
+
+	return cs
+}
+
+// applyStyle applies the style to the cell style, the new font and border start from the existing ones.
+func (x *Xlsx) applyStyle(cs spreadsheet.CellStyle, s Style) {
+	ss := x.workbook.StyleSheet
+	xf := ss.X().CellXfs.Xf[cs.Index()]
+
+	if s.Bold || s.Italic || s.FontName != "" || s.FontSize > 0 || s.FontColor != "" {
+		font := ss.AddFont()
+		if fonts := ss.X().Fonts; xf.FontIdAttr != nil && int(*xf.FontIdAttr) < len(fonts.Font) {
+			*font.X() = *fonts.Font[*xf.FontIdAttr]
+		}
+
+		if s.Bold {
+			font.SetBold(true)
+		}
+
+		if s.Italic {
+			font.SetItalic(true)
+		}
+
+		if s.FontName != "" {
+			font.SetName(s.FontName)
+		}
+
+		if s.FontSize > 0 {
+			font.SetSize(s.FontSize)
+		}
+
+		if s.FontColor != "" {
+			font.SetColor(color.FromHex(s.FontColor))
+		}
+
+		cs.SetFont(font)
+	}
+
+	if s.Fill != "" {
+		fill := ss.Fills().AddFill()
+		pf := fill.SetPatternFill()
+		pf.SetPattern(sml.ST_PatternTypeSolid)
+		pf.SetFgColor(color.FromHex(s.Fill))
+		cs.SetFill(fill)
+	}
+
+	if s.Border {
+		c := color.Black
+		if s.BorderColor != "" {
+			c = color.FromHex(s.BorderColor)
+		}
+
+		b := ss.AddBorder()
+		if borders := ss.X().Borders; xf.BorderIdAttr != nil && int(*xf.BorderIdAttr) < len(borders.Border) {
+			*b.X() = *borders.Border[*xf.BorderIdAttr]
+		}
+
+		b.SetLeft(sml.ST_BorderStyleThin, c)
+		b.SetRight(sml.ST_BorderStyleThin, c)
+		b.SetTop(sml.ST_BorderStyleThin, c)
+		b.SetBottom(sml.ST_BorderStyleThin, c)
+		cs.SetBorder(b)
+	}
+
+	if a := parseHorizontalAlignment(s.Horizontal); a != sml.ST_HorizontalAlignmentUnset {
+		cs.SetHorizontalAlignment(a)
+	}
+
+	if a := parseVerticalAlignment(s.Vertical); a != sml.ST_VerticalAlignmentUnset {
+		cs.SetVerticalAlignment(a)
+	}
+
+	if s.Wrap {
+		cs.SetWrapped(true)
+	}
+
+	if s.NumberFormat != "" {
+		cs.SetNumberFormat(s.NumberFormat)
+	}
+}
+
+func parseHorizontalAlignment(s string) sml.ST_HorizontalAlignment {
+	switch strings.ToLower(s) {
+	case "left":
+		return sml.ST_HorizontalAlignmentLeft
+	case "center":
+		return sml.ST_HorizontalAlignmentCenter
+	case "right":
+		return sml.ST_HorizontalAlignmentRight
+	default:
+		return sml.ST_HorizontalAlignmentUnset
+	}
+}
+
+func parseVerticalAlignment(s string) sml.ST_VerticalAlignment {
+	switch strings.ToLower(s) {
+	case "top":
+		return sml.ST_VerticalAlignmentTop
+	case "center":
+		return sml.ST_VerticalAlignmentCenter
+	case "bottom":
+		return sml.ST_VerticalAlignmentBottom
+	default:
+		return sml.ST_VerticalAlignmentUnset
+	}
+}
@@ -0,0 +1,96 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type salary struct {
+	Name   string  `title:"姓名"`
+	Amount float64 `title:"金额" style:"money"`
+}
+
+func TestStyle(t *testing.T) {
+	x, _ := xlsx.New(xlsx.WithStyles(map[string]xlsx.Style{
+		"money": {NumberFormat: "#,##0.00", Horizontal: "right"},
+	}))
+	defer x.Close()
+
+	title := xlsx.Style{Bold: true, Fill: "DDEBF7", Border: true, Horizontal: "center", Wrap: true}
+	err := x.Write([]salary{
+		{Name: "张三", Amount: 1234.5},
+		{Name: "李四", Amount: 6789},
+	}, xlsx.WithTitleStyle(title))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	// the same style should reuse the same stylesheet entry.
+	assert.NotNil(t, sheet.Cell("A1").X().SAttr)
+	assert.Equal(t, *sheet.Cell("A1").X().SAttr, *sheet.Cell("B1").X().SAttr)
+	assert.NotNil(t, sheet.Cell("B2").X().SAttr)
+	assert.Equal(t, *sheet.Cell("B2").X().SAttr, *sheet.Cell("B3").X().SAttr)
+	assert.NotEqual(t, *sheet.Cell("A1").X().SAttr, *sheet.Cell("B2").X().SAttr)
+	assert.Nil(t, sheet.Cell("A2").X().SAttr)
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var salaries []salary
+
+	assert.Nil(t, x2.Read(&salaries))
+	assert.Equal(t, []salary{{Name: "张三", Amount: 1234.5}, {Name: "李四", Amount: 6789}}, salaries)
+}
+
+func TestStyleTemplateMerge(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("姓名")
+	sheet.Cell("B1").SetString("金额")
+
+	cs := wb.StyleSheet.AddCellStyle()
+	font := wb.StyleSheet.AddFont()
+	font.SetItalic(true)
+	cs.SetFont(font)
+	cs.SetHorizontalAlignment(sml.ST_HorizontalAlignmentCenter)
+
+	sheet.Cell("A2").SetStyle(cs)
+	sheet.Cell("B2").SetStyle(cs)
+
+	x, _ := xlsx.New(xlsx.WithTemplate(saveWorkbook(t, wb)), xlsx.WithStyles(map[string]xlsx.Style{
+		"money": {Bold: true, NumberFormat: "#,##0.00"},
+	}))
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]salary{{Name: "张三", Amount: 1234.5}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = wb.Sheets()[0]
+	nameStyle := wb.StyleSheet.GetCellStyle(*sheet.Cell("A2").X().SAttr)
+	assert.Equal(t, 1, len(nameStyle.GetFont().I))
+	assert.Equal(t, 0, len(nameStyle.GetFont().B))
+
+	// the named style is merged over the template style, instead of replacing it.
+	amountStyle := wb.StyleSheet.GetCellStyle(*sheet.Cell("B2").X().SAttr)
+	assert.Equal(t, 1, len(amountStyle.GetFont().I))
+	assert.Equal(t, 1, len(amountStyle.GetFont().B))
+	assert.Equal(t, sml.ST_HorizontalAlignmentCenter, amountStyle.GetHorizontalAlignment())
+	assert.Equal(t, "1,234.50", sheet.Cell("B2").GetFormattedValue())
+}
@@ -0,0 +1,215 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Subtotals defines the group-by subtotals inserted between the groups of the written rows,
+// like the Subtotal feature of Excel. The rows should be sorted by the group-by fields in advance.
+type Subtotals struct {
+	// GroupBy is the field names to group by, a new group starts when any of the field values changes.
+	GroupBy []string
+	// Aggregates is the aggregates per field name, like {"Amount": "sum"},
+	// the fields tagged like `total:"sum"` are aggregated too.
+	Aggregates map[string]string
+	// Label is the label written in the first group-by column of the subtotal row,
+	// {group} is replaced with the group value, default "{group} 小计".
+	Label string
+	// GrandTotalLabel appends a grand total row under all the groups when not empty.
+	GrandTotalLabel string
+	// Style is the style of the subtotal and grand total rows.
+	Style Style
+}
+
+// DefaultSubtotalLabel is the default label of the subtotal rows.
+const DefaultSubtotalLabel = "{group} 小计"
+
+// WithSubtotals inserts subtotal rows (as SUBTOTAL formulas) after each group, and a grand total row optionally.
+func WithSubtotals(v Subtotals) WriteOptionFn {
+	return func(o *WriteOption) {
+		if v.Label == "" {
+			v.Label = DefaultSubtotalLabel
+		}
+
+		o.Subtotals = &v
+
+		if v.GrandTotalLabel != "" {
+			o.TotalsRow = true
+			o.TotalsLabel = v.GrandTotalLabel
+
+			if o.TotalsStyle.IsEmpty() {
+				o.TotalsStyle = v.Style
+			}
+		}
+	}
+}
+
+// aggregateOf returns the aggregate of the field from the subtotals or the total tag.
+func (o WriteOption) aggregateOf(f reflect.StructField) string {
+	if o.Subtotals != nil {
+		if a, ok := o.Subtotals.Aggregates[f.Name]; ok {
+			return a
+		}
+	}
+
+	return f.Tag.Get("total")
+}
+
+// SubtotalFunctionNum returns the function number of the SUBTOTAL function for the aggregate name,
+// which ignores the other subtotals in the range.
+func SubtotalFunctionNum(aggregate string) (int, error) {
+	switch strings.ToLower(aggregate) {
+	case "average", "avg":
+		return 1, nil
+	case "count":
+		return 2, nil // nolint:gomnd
+	case "counta":
+		return 3, nil // nolint:gomnd
+	case "max":
+		return 4, nil // nolint:gomnd
+	case "min":
+		return 5, nil // nolint:gomnd
+	case "sum":
+		return 9, nil // nolint:gomnd
+	default:
+		return 0, fmt.Errorf("%s: %w", aggregate, ErrUnknownAggregate)
+	}
+}
+
+// aggregateFormula creates the formula to aggregate the column from startRow to endRow,
+// SUBTOTAL is used when there are subtotals, so the subtotals are not counted twice.
+func (o WriteOption) aggregateFormula(aggregate, column string, startRow, endRow uint32) (string, error) {
+	if o.Subtotals != nil {
+		num, err := SubtotalFunctionNum(aggregate)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("SUBTOTAL(%d,%s%d:%s%d)", num, column, startRow, column, endRow), nil
+	}
+
+	fn, err := AggregateFunction(aggregate)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s(%s%d:%s%d)", fn, column, startRow, column, endRow), nil
+}
+
+// subtotaler writes the beans and inserts the subtotal rows between the groups.
+type subtotaler struct {
+	x       *Xlsx
+	r       *run
+	tfs     []TitleField
+	nextRow func() uint32 // allocates a new row for the subtotal row
+
+	groupFields []reflect.StructField
+	key, group  string
+
+	firstRow, startRow, lastRow uint32
+}
+
+// ErrUnknownGroupByField defines the error of the GroupBy field name which is not found in the bean.
+var ErrUnknownGroupByField = fmt.Errorf("unknown group-by field")
+
+func (x *Xlsx) newSubtotaler(r *run, tfs []TitleField, nextRow func() uint32) (*subtotaler, error) {
+	s := &subtotaler{x: x, r: r, tfs: tfs, nextRow: nextRow}
+
+	if sub := r.writeOption.Subtotals; sub != nil {
+		for _, name := range sub.GroupBy {
+			f, ok := r.beanType.FieldByName(name)
+			if !ok {
+				return nil, fmt.Errorf("%s of %s: %w", name, r.beanType, ErrUnknownGroupByField)
+			}
+
+			s.groupFields = append(s.groupFields, f)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *subtotaler) active() bool { return len(s.groupFields) > 0 }
+
+// write writes the i-th bean by writeBean, a subtotal row is inserted before it when the group changes.
+func (s *subtotaler) write(i int, writeBean func(v reflect.Value) (uint32, error)) error {
+	bean := s.r.elem(i)
+
+	if s.active() {
+		values := make([]string, len(s.groupFields))
+		for j, f := range s.groupFields {
+			values[j] = getFieldValue(f, bean)
+		}
+
+		if key := strings.Join(values, "\x00"); key != s.key || s.startRow == 0 {
+			if err := s.flush(); err != nil {
+				return err
+			}
+
+			s.key, s.group = key, strings.Join(values, " ")
+		}
+	}
+
+	rowNum, err := writeBean(bean)
+	if err != nil {
+		return err
+	}
+	s.r.rowBeans = append(s.r.rowBeans, i)
+
+	if s.firstRow == 0 {
+		s.firstRow = rowNum
+	}
+
+	if s.startRow == 0 {
+		s.startRow = rowNum
+	}
+
+	s.lastRow = rowNum
+
+	return nil
+}
+
+// flush writes the subtotal row of the current group.
+func (s *subtotaler) flush() error {
+	if !s.active() || s.startRow == 0 {
+		return nil
+	}
+
+	rowNum := s.nextRow()
+	s.r.rowBeans = append(s.r.rowBeans, -1)
+	err := s.writeSubtotalRow(rowNum)
+	s.startRow = 0
+	s.lastRow = rowNum
+
+	return err
+}
+
+func (s *subtotaler) writeSubtotalRow(rowNum uint32) error {
+	option := s.r.writeOption
+	row := s.x.currentSheet.Row(rowNum)
+	labelField := s.groupFields[0].Name
+
+	for _, tf := range s.tfs {
+		cell := row.Cell(tf.Column)
+
+		if aggregate := option.aggregateOf(tf.StructField); aggregate != "" {
+			f, err := option.aggregateFormula(aggregate, tf.Column, s.startRow, s.lastRow)
+			if err != nil {
+				return err
+			}
+
+			cell.SetFormulaRaw(f)
+		} else if tf.StructField.Name == labelField {
+			cell.SetString(strings.ReplaceAll(option.Subtotals.Label, "{group}", s.group))
+		}
+
+		s.x.setFieldStyle(cell, tf.StructField)
+		s.x.setCellStyle(cell, option.Subtotals.Style)
+	}
+
+	s.x.rowWritten(rowNum)
+
+	return nil
+}
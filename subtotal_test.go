@@ -0,0 +1,83 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type regionSale struct {
+	Region string  `title:"区域" merge:"true"`
+	City   string  `title:"城市"`
+	Amount float64 `title:"金额" total:"sum"`
+	Orders int     `title:"订单数"`
+}
+
+func TestSubtotals(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]regionSale{
+		{Region: "华东", City: "上海", Amount: 100, Orders: 1},
+		{Region: "华东", City: "杭州", Amount: 200, Orders: 2},
+		{Region: "华北", City: "北京", Amount: 300, Orders: 3},
+	}, xlsx.WithSubtotals(xlsx.Subtotals{
+		GroupBy:         []string{"Region"},
+		Aggregates:      map[string]string{"Orders": "count"},
+		GrandTotalLabel: "总计",
+		Style:           xlsx.Style{Bold: true},
+	}), xlsx.WithMergeColsMode(xlsx.MergeCols))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	assert.Equal(t, "华东 小计", sheet.Cell("A4").GetString())
+	assert.Equal(t, "SUBTOTAL(9,C2:C3)", sheet.Cell("C4").GetFormula())
+	assert.Equal(t, "SUBTOTAL(2,D2:D3)", sheet.Cell("D4").GetFormula())
+	assert.Equal(t, "北京", sheet.Cell("B5").GetString())
+	assert.Equal(t, "华北 小计", sheet.Cell("A6").GetString())
+	assert.Equal(t, "SUBTOTAL(9,C5:C5)", sheet.Cell("C6").GetFormula())
+	assert.Equal(t, "总计", sheet.Cell("A7").GetString())
+	assert.Equal(t, "SUBTOTAL(9,C2:C6)", sheet.Cell("C7").GetFormula())
+
+	refs := make([]string, 0)
+	for _, mc := range sheet.MergedCells() {
+		refs = append(refs, mc.Reference())
+	}
+
+	assert.Equal(t, []string{"A2:A3"}, refs)
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	type regionTotal struct {
+		Region string  `title:"区域"`
+		Amount float64 `title:"金额"`
+	}
+
+	var totals []regionTotal
+
+	assert.Nil(t, x2.Read(&totals))
+	assert.Equal(t, regionTotal{Region: "华东 小计", Amount: 300}, totals[2])
+	assert.Equal(t, regionTotal{Region: "总计", Amount: 600}, totals[5])
+}
+
+func TestSubtotalsUnknownGroupBy(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]regionSale{{Region: "华东", City: "上海", Amount: 100, Orders: 1}},
+		xlsx.WithSubtotals(xlsx.Subtotals{GroupBy: []string{"Province"}}))
+	assert.True(t, errors.Is(err, xlsx.ErrUnknownGroupByField))
+	assert.Contains(t, err.Error(), "Province")
+}
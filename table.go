@@ -0,0 +1,324 @@
+package xlsx
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// WithReadSheet reads the sheet whose name contains the name, instead of the sheet tag or the first sheet,
+// for Read and ReadTable.
+func WithReadSheet(name string) ReadOptionFn {
+	return func(o *ReadOption) {
+		o.SheetName = name
+	}
+}
+
+// WithTitleRow defines the title row number (1-based) of Read and ReadTable,
+// by default the title row is located by WithTitles, or by the struct titles in Read
+// and the first non-empty row in ReadTable.
+func WithTitleRow(rowNum uint32) ReadOptionFn {
+	return func(o *ReadOption) {
+		o.TitleRowNum = rowNum
+	}
+}
+
+// WithTitles locates the title row of Read and ReadTable by the titles, which should all be contained in the row,
+// the title like =Name matches the cell text strictly. Read locates the struct titles in that row then.
+func WithTitles(titles ...string) ReadOptionFn {
+	return func(o *ReadOption) {
+		o.Titles = titles
+	}
+}
+
+// WithKeepEmptyRows keeps the empty rows in Read and ReadTable, like the ignoreEmptyRows:"false" tag in Read.
+func WithKeepEmptyRows() ReadOptionFn {
+	return func(o *ReadOption) {
+		o.KeepEmptyRows = true
+	}
+}
+
+// CellType is the type of the table cell value.
+type CellType int
+
+const (
+	// CellEmpty is the empty cell.
+	CellEmpty CellType = iota
+	// CellString is the string cell.
+	CellString
+	// CellNumber is the number cell.
+	CellNumber
+	// CellBool is the boolean cell.
+	CellBool
+	// CellTime is the number cell with a date or time format.
+	CellTime
+	// CellFormula is the formula cell, the value is the cached or evaluated result by the FormulaMode.
+	CellFormula
+)
+
+// CellValue is the typed value of a table cell.
+type CellValue struct {
+	Type CellType
+	// Text is the text of the cell, as Read gets.
+	Text   string
+	Number float64
+	Bool   bool
+	Time   time.Time
+	// Formula is the formula text without the leading = of the formula cell.
+	Formula string
+}
+
+// String returns the text of the cell.
+func (v CellValue) String() string { return v.Text }
+
+// TableRow is a data row of the table.
+type TableRow struct {
+	RowNum uint32
+	Cells  []CellValue
+}
+
+// Table is the headers and the rows read from a sheet without a struct.
+type Table struct {
+	// Headers are the non-empty texts of the title row.
+	Headers []string
+	// Columns are the column letters of the headers, like A, B.
+	Columns     []string
+	TitleRowNum uint32
+	Rows        []TableRow
+}
+
+// Maps returns the rows as maps from the headers to the cell texts.
+func (t *Table) Maps() []map[string]string {
+	maps := make([]map[string]string, len(t.Rows))
+
+	for i, row := range t.Rows {
+		m := make(map[string]string, len(t.Headers))
+		for j, h := range t.Headers {
+			m[h] = row.Cells[j].Text
+		}
+
+		maps[i] = m
+	}
+
+	return maps
+}
+
+// ReadTable reads the sheet into a table of the headers and the typed cell values, without a struct.
+// The title row is located by WithTitleRow, WithTitles or the first non-empty row, the empty rows are skipped
+// unless WithKeepEmptyRows, and the merged cells and formulas are handled as Read does.
+func (x *Xlsx) ReadTable(readOptionFns ...ReadOptionFn) (*Table, error) {
+	return x.ReadTableContext(context.Background(), readOptionFns...)
+}
+
+// ReadTableContext reads the sheet into a table like ReadTable,
+// and stops with the error of ctx when it is canceled between the rows.
+func (x *Xlsx) ReadTableContext(ctx context.Context, readOptionFns ...ReadOptionFn) (table *Table, err error) {
+	defer x.phaseDone("read", time.Now(), &err)
+
+	x.ctx = ctx
+	defer func() { x.ctx = nil }()
+
+	x.resetReadOption(readOptionFns)
+
+	if !x.hasInput() {
+		return nil, ErrNoExcelRead
+	}
+
+	x.currentSheet = x.findReadSheet(x.workbook, "")
+	if !x.currentSheet.IsValid() {
+		return nil, ErrNoExcelRead
+	}
+
+	if err := x.option.Limits.checkSheet(x.currentSheet, x.workbook.SharedStrings); err != nil {
+		return nil, err
+	}
+
+	rows := x.currentSheet.Rows()
+
+	titledRowNum, err := x.findTableTitleRow(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Table{TitleRowNum: titledRowNum}
+
+	for _, cell := range x.rowCells(x.currentSheet.Row(titledRowNum)) {
+		col, err := cell.Column()
+		if err != nil {
+			continue
+		}
+
+		if s := x.getCellString(cell); s != "" {
+			t.Headers = append(t.Headers, s)
+			t.Columns = append(t.Columns, col)
+		}
+	}
+
+	for _, row := range x.findTemplateRows(titledRowNum, rows) {
+		if err := x.ctxErr(); err != nil {
+			return nil, err
+		}
+
+		cells := make([]CellValue, len(t.Columns))
+		empty := true
+
+		for i, col := range t.Columns {
+			cells[i] = x.getCellValue(row.Cell(col))
+			empty = empty && cells[i].Text == ""
+		}
+
+		if empty && !x.readOption.KeepEmptyRows {
+			continue
+		}
+
+		t.Rows = append(t.Rows, TableRow{RowNum: row.RowNumber(), Cells: cells})
+		x.rowRead(row.RowNumber())
+	}
+
+	return t, nil
+}
+
+func (x *Xlsx) findTableTitleRow(rows []spreadsheet.Row) (uint32, error) {
+	if n := x.readOption.TitleRowNum; n > 0 {
+		return n, nil
+	}
+
+	if len(x.readOption.Titles) > 0 {
+		titles := make([]TitleField, len(x.readOption.Titles))
+		for i, t := range x.readOption.Titles {
+			titles[i] = TitleField{Title: MakeTitle(t)}
+		}
+
+		return x.findTitledRow(titles, true, rows)
+	}
+
+	for i, row := range rows {
+		if i > 5 { // nolint:gomnd
+			break
+		}
+
+		for _, cell := range x.rowCells(row) {
+			if GetCellString(cell) != "" {
+				return row.RowNumber(), nil
+			}
+		}
+	}
+
+	return 0, ErrFailToLocationTitleRow
+}
+
+// locateReadTitleRow locates the title row of Read, only in the row given by WithTitleRow or WithTitles if any.
+func (x *Xlsx) locateReadTitleRow(titles []TitleField, customizedTitle bool) (*templateLocation, error) {
+	if x.readOption.TitleRowNum == 0 && len(x.readOption.Titles) == 0 {
+		return x.locateTitleRow(titles, customizedTitle, false)
+	}
+
+	if !x.hasInput() {
+		return &templateLocation{}, ErrNoExcelRead
+	}
+
+	sheet := x.titleSheet()
+	rows := sheet.Rows()
+
+	titledRowNum, err := x.findTableTitleRow(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if row.RowNumber() == titledRowNum {
+			return x.locateTitleRowIn(titles, customizedTitle, []spreadsheet.Row{row}, rows)
+		}
+	}
+
+	return nil, ErrFailToLocationTitleRow
+}
+
+// getCellValue returns the typed value of the cell.
+func (x *Xlsx) getCellValue(c spreadsheet.Cell) CellValue {
+	text := x.getCellString(c)
+	c = x.resolveMergedCell(c)
+	v := CellValue{Text: text}
+
+	switch {
+	case c.HasFormula():
+		v.Type = CellFormula
+		v.Formula = c.GetFormula()
+		v.Number, _ = strconv.ParseFloat(text, 64)
+	case text == "":
+		v.Type = CellEmpty
+	case c.X().TAttr == sml.ST_CellTypeB:
+		v.Type = CellBool
+		v.Bool, _ = c.GetValueAsBool()
+	case c.IsNumber():
+		v.Number, _ = c.GetValueAsNumber()
+
+		if x.isDateCell(c) {
+			if t, err := c.GetValueAsTime(); err == nil {
+				v.Type, v.Time = CellTime, t
+				break
+			}
+		}
+
+		v.Type = CellNumber
+	default:
+		v.Type = CellString
+	}
+
+	return v
+}
+
+// isDateCell tells whether the number format of the cell is a date or time format.
+func (x *Xlsx) isDateCell(c spreadsheet.Cell) bool {
+	if c.X().SAttr == nil {
+		return false
+	}
+
+	style := x.workbook.StyleSheet.GetCellStyle(*c.X().SAttr)
+	if !style.HasNumberFormat() {
+		return false
+	}
+
+	id := style.NumberFormat()
+
+	// the built-in date and time formats.
+	if id >= 14 && id <= 22 || id >= 45 && id <= 47 { // nolint:gomnd
+		return true
+	}
+
+	if numFmts := x.workbook.StyleSheet.X().NumFmts; numFmts != nil {
+		for _, f := range numFmts.NumFmt {
+			if f.NumFmtIdAttr == id {
+				return isDateFormat(f.FormatCodeAttr)
+			}
+		}
+	}
+
+	return false
+}
+
+// isDateFormat tells whether the number format code contains the date or time parts outside the quotes and brackets.
+func isDateFormat(code string) bool {
+	inQuote, inBracket := false, false
+
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case strings.ContainsRune("ymdhs", r):
+			return true
+		}
+	}
+
+	return false
+}
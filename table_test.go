@@ -0,0 +1,101 @@
+package xlsx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestReadTable(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("员工")
+	sheet.Cell("A1").SetString("员工花名册")
+	sheet.Cell("A2").SetString("姓名")
+	sheet.Cell("B2").SetString("年龄")
+	sheet.Cell("C2").SetString("入职")
+	sheet.Cell("D2").SetString("在职")
+	sheet.Cell("E2").SetString("明年")
+	sheet.Cell("A3").SetString("张三")
+	sheet.Cell("B3").SetNumber(18)
+	sheet.Cell("C3").SetDateWithStyle(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	sheet.Cell("D3").SetBool(true)
+	sheet.Cell("E3").SetFormulaRaw("B3+1")
+	sheet.Cell("A4").SetString("")
+	sheet.Cell("B5").SetNumber(20)
+	sheet.Cell("B6").SetNumber(21)
+	sheet.Cell("A5").SetString("李四")
+	sheet.AddMergedCells("A5", "A6")
+
+	x, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	table, err := x.ReadTable(xlsx.WithTitles("姓名", "年龄"), xlsx.WithMergedCellsFill())
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), table.TitleRowNum)
+	assert.Equal(t, []string{"姓名", "年龄", "入职", "在职", "明年"}, table.Headers)
+	assert.Equal(t, []string{"A", "B", "C", "D", "E"}, table.Columns)
+	assert.Equal(t, 3, len(table.Rows))
+
+	cells := table.Rows[0].Cells
+	assert.Equal(t, xlsx.CellValue{Type: xlsx.CellString, Text: "张三"}, cells[0])
+	assert.Equal(t, xlsx.CellValue{Type: xlsx.CellNumber, Text: "18", Number: 18}, cells[1])
+	assert.Equal(t, xlsx.CellTime, cells[2].Type)
+	assert.Equal(t, "2020-01-02", cells[2].Time.Format("2006-01-02"))
+	assert.Equal(t, xlsx.CellBool, cells[3].Type)
+	assert.True(t, cells[3].Bool)
+	assert.Equal(t, xlsx.CellValue{Type: xlsx.CellFormula, Text: "19", Number: 19, Formula: "B3+1"}, cells[4])
+
+	assert.Equal(t, uint32(6), table.Rows[2].RowNum)
+	assert.Equal(t, "李四", table.Maps()[2]["姓名"])
+	assert.Equal(t, "21", table.Maps()[2]["年龄"])
+
+	table, err = x.ReadTable(xlsx.WithReadSheet("员工"), xlsx.WithTitleRow(2), xlsx.WithKeepEmptyRows())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(table.Rows))
+	assert.Equal(t, "", table.Maps()[3]["姓名"])
+
+	table, err = x.ReadTable()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"员工花名册"}, table.Headers)
+}
+
+func TestReadWithTableOptions(t *testing.T) {
+	wb := spreadsheet.New()
+	other := wb.AddSheet()
+	other.SetName("汇总")
+	other.Cell("A1").SetString("会员总数")
+	other.Cell("B1").SetString("其中：新增")
+	other.Cell("C1").SetString("其中：有效")
+	other.Cell("A2").SetNumber(1)
+
+	sheet := wb.AddSheet()
+	sheet.SetName("会员")
+	sheet.Cell("A1").SetString("会员总数说明")
+	sheet.Cell("A2").SetString("会员总数")
+	sheet.Cell("B2").SetString("其中：新增")
+	sheet.Cell("C2").SetString("其中：有效")
+	sheet.Cell("A3").SetNumber(100)
+	sheet.Row(4)
+	sheet.Cell("A5").SetNumber(200)
+
+	x, _ := xlsx.New(xlsx.WithExcel(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	var stats []memberStat
+
+	assert.Nil(t, x.Read(&stats, xlsx.WithReadSheet("会员"), xlsx.WithTitleRow(2)))
+	assert.Equal(t, []memberStat{{Total: 100}, {Total: 200}}, stats)
+
+	assert.Nil(t, x.Read(&stats, xlsx.WithReadSheet("会员"), xlsx.WithTitles("其中：新增"), xlsx.WithKeepEmptyRows()))
+	assert.Equal(t, []memberStat{{Total: 100}, {}, {Total: 200}}, stats)
+
+	assert.Equal(t, xlsx.ErrFailToLocationTitleRow,
+		x.Read(&stats, xlsx.WithReadSheet("会员"), xlsx.WithTitleRow(3)))
+
+	assert.Nil(t, x.Read(&stats, xlsx.WithReadSheet("汇总")))
+	assert.Equal(t, []memberStat{{Total: 1}}, stats)
+}
@@ -0,0 +1,235 @@
+package xlsx
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+// wholeCellVar returns the var of the placeholder value which is only one placeholder without pipes, like {{Amount}}.
+func wholeCellVar(pl PlaceholderValue) (string, bool) {
+	if len(pl.Parts) == 1 && pl.Parts[0].Var != "" && len(pl.Parts[0].Pipes) == 0 {
+		return pl.Parts[0].Var, true
+	}
+
+	return "", false
+}
+
+// placeholderFieldTypes collects the fields by the placeholder var names with the prefix,
+// the nested struct fields are collected by the paths like Owner.Name.
+func placeholderFieldTypes(out map[string]reflect.StructField, prefix string, fields []reflect.StructField) {
+	collectFieldTypes(out, prefix, fields, make(map[reflect.Type]bool))
+}
+
+// collectFieldTypes collects the field types like placeholderFieldTypes, the struct types being visited
+// on the path are skipped, so the self-referential types like a Parent *node field do not recurse forever.
+func collectFieldTypes(out map[string]reflect.StructField, prefix string, fields []reflect.StructField,
+	visiting map[reflect.Type]bool,
+) {
+	for _, f := range fields {
+		name := prefix + placeholderName(f)
+
+		t := f.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct || t == timeType {
+			out[name] = f
+			continue
+		}
+
+		if isValueStruct(f.Type) {
+			out[name] = f
+		}
+
+		if visiting[t] {
+			continue
+		}
+
+		visiting[t] = true
+		collectFieldTypes(out, name+".", exportedFields(t), visiting)
+		delete(visiting, t)
+	}
+}
+
+// setPlaceholderCell sets the interpolated placeholder value into the cell,
+// the whole cell placeholder of a number, bool or time field is set as the typed value,
+// so the formulas referring to the cell keep working.
+func (x *Xlsx) setPlaceholderCell(cell spreadsheet.Cell, pl PlaceholderValue,
+	vars map[string]string, fieldTypes map[string]reflect.StructField,
+) {
+	if name, ok := wholeCellVar(pl); ok {
+		if f, ok := fieldTypes[name]; ok && x.setTypedCell(cell, f, vars[name]) {
+			return
+		}
+	}
+
+	cell.SetString(pl.Interpolate(vars))
+}
+
+// setTypedCell sets the value s of the field f as the typed value into the cell,
+// and returns false when the field is not a number, bool or time.
+func (x *Xlsx) setTypedCell(cell spreadsheet.Cell, f reflect.StructField, s string) bool {
+	if s == "" {
+		return false
+	}
+
+	switch {
+	case f.Type == timeType:
+		t, err := parseTime(f.Tag, s)
+		if err != nil {
+			x.typedCellFailed(cell, f, err)
+			return false
+		}
+
+		cell.SetDate(t)
+		x.setDateNumberFormat(cell, f.Tag)
+	case f.Type.Kind() == reflect.Bool:
+		cell.SetBool(ParseBool(s, false))
+	case isNumberKind(f.Type.Kind()):
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			x.typedCellFailed(cell, f, err)
+			return false
+		}
+
+		cell.SetNumber(v)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// typedCellFailed reports the value which fails to convert to the type of the field, it is written as text instead.
+func (x *Xlsx) typedCellFailed(cell spreadsheet.Cell, f reflect.StructField, err error) {
+	x.logger().Warn("failed to write typed value, written as text", "field", f.Name, "cell", cell.Reference(), "error", err)
+	x.cellFailed(cell.Reference(), err)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExcelDateFormat converts the time format in java to the number format of excel,
+// like yyyy-MM-dd HH:mm:ss to yyyy-mm-dd hh:mm:ss.
+func ExcelDateFormat(layout string) string {
+	return strings.ReplaceAll(strings.ToLower(layout), ".sss", ".000")
+}
+
+// dateStyleKey is the key of the cached date styles, by the cell style index and the date layout.
+type dateStyleKey struct {
+	styleIndex uint32
+	hasStyle   bool
+	layout     string
+}
+
+// setDateNumberFormat sets the date number format by the format tag into the cell,
+// unless the cell already has a date number format in the template.
+// The other parts of the cell style like the font and border are kept,
+// and the date style is added once for each cell style and layout.
+func (x *Xlsx) setDateNumberFormat(cell spreadsheet.Cell, tag reflect.StructTag) {
+	if x.isDateCell(cell) {
+		return
+	}
+
+	layout := tag.Get("format")
+	if layout == "" {
+		layout = "yyyy-MM-dd HH:mm:ss"
+	}
+
+	ss := x.workbook.StyleSheet
+	key := dateStyleKey{layout: layout}
+
+	if idx := cell.X().SAttr; idx != nil && int(*idx) < len(ss.X().CellXfs.Xf) {
+		key.styleIndex, key.hasStyle = *idx, true
+	}
+
+	if index, ok := x.dateStyles[key]; ok {
+		cell.X().SAttr = &index
+		return
+	}
+
+	cs := ss.AddCellStyle()
+
+	if key.hasStyle {
+		*ss.X().CellXfs.Xf[cs.Index()] = *ss.X().CellXfs.Xf[key.styleIndex]
+	}
+
+	cs.SetNumberFormat(ExcelDateFormat(layout))
+	cell.SetStyle(cs)
+
+	if x.dateStyles == nil {
+		x.dateStyles = make(map[dateStyleKey]uint32)
+	}
+
+	x.dateStyles[key] = cs.Index()
+}
+
+// placeholderCellString returns the string of the placeholder cell for reading,
+// the typed time cell of the whole cell placeholder is formatted by the format tag of the field.
+func (x *Xlsx) placeholderCellString(c spreadsheet.Cell, pl PlaceholderValue,
+	fieldTypes map[string]reflect.StructField,
+) string {
+	if name, ok := wholeCellVar(pl); ok {
+		if f, ok := fieldTypes[name]; ok && f.Type == timeType && c.IsNumber() {
+			if t, err := c.GetValueAsTime(); err == nil {
+				return formatTime(f.Tag, t)
+			}
+		}
+	}
+
+	return x.getCellString(c)
+}
+
+// defaultTimeLayout is the layout of the time values without the format tag.
+const defaultTimeLayout = "2006-01-02 15:04:05"
+
+// convertTimeLayout converts the time value from the layout from to the layout to,
+// the value is kept when any layout is empty or it fails to parse.
+func convertTimeLayout(value, from, to string) string {
+	if from == "" || to == "" || from == to {
+		return value
+	}
+
+	t, err := time.ParseInLocation(from, value, time.Local)
+	if err != nil {
+		return value
+	}
+
+	return t.Format(to)
+}
+
+// placeholderTimeLayouts collects the layouts of the time fields with the format tags by the var names,
+// the fields of the loop elements are collected by the names like .Date.
+func placeholderTimeLayouts(fields []reflect.StructField) map[string]string {
+	fieldTypes := make(map[string]reflect.StructField)
+	placeholderFieldTypes(fieldTypes, "", fields)
+
+	for _, f := range fieldTypes {
+		if t := f.Type; t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct && t.Elem() != timeType {
+			placeholderFieldTypes(fieldTypes, ".", exportedFields(t.Elem()))
+		}
+	}
+
+	layouts := make(map[string]string)
+
+	for name, f := range fieldTypes {
+		if format := f.Tag.Get("format"); f.Type == timeType && format != "" {
+			layouts[name] = ParseJavaTimeFormat(format)
+		}
+	}
+
+	return layouts
+}
@@ -0,0 +1,291 @@
+package xlsx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+// vertical tells the layout:"vertical" tag, which lays out the titles down a label column
+// and the values in the adjacent columns, one column per record.
+func (r *run) vertical() bool {
+	return r.FindTtag("layout") == "vertical"
+}
+
+// verticalLabel is a located label cell of the vertical layout.
+type verticalLabel struct {
+	TitleField
+	RowNum uint32
+	// ValueColIdx is the column index of the value cell of the first record.
+	ValueColIdx uint32
+}
+
+func (l verticalLabel) valueCell(sheet spreadsheet.Sheet, record int) spreadsheet.Cell {
+	return sheet.Row(l.RowNum).Cell(reference.IndexToColumn(l.ValueColIdx + uint32(record)))
+}
+
+// locateVerticalLabels locates the label cells of the titles in the sheet,
+// the cells equal to the titles are preferred to the cells containing the titles.
+// The value cell is the adjacent cell right to the label cell, or right to the merged range of the label cell.
+func (x *Xlsx) locateVerticalLabels(sheet spreadsheet.Sheet, titles []TitleField) []verticalLabel {
+	mergedEnds := make(map[string]uint32)
+
+	for _, mc := range sheet.MergedCells() {
+		if from, to, err := reference.ParseRangeReference(mc.Reference()); err == nil {
+			mergedEnds[from.String()] = to.ColumnIdx
+		}
+	}
+
+	located := make(map[int]verticalLabel)
+	used := make(map[string]bool)
+
+	for _, strict := range []bool{true, false} {
+		for _, row := range sheet.Rows() {
+			for _, cell := range x.rowCells(row) {
+				s := GetCellString(cell)
+				if s == "" || used[cell.Reference()] {
+					continue
+				}
+
+				for i, title := range titles {
+					if _, ok := located[i]; ok {
+						continue
+					}
+
+					if strict && s != title.Title.Text || !strict && !title.Title.Matches(s) {
+						continue
+					}
+
+					ref, err := reference.ParseCellReference(cell.Reference())
+					if err != nil {
+						continue
+					}
+
+					valueColIdx := ref.ColumnIdx + 1
+					if end, ok := mergedEnds[ref.String()]; ok {
+						valueColIdx = end + 1
+					}
+
+					title.Column = ref.Column
+					located[i] = verticalLabel{TitleField: title, RowNum: ref.RowIdx, ValueColIdx: valueColIdx}
+					used[cell.Reference()] = true
+
+					break
+				}
+			}
+		}
+	}
+
+	labels := make([]verticalLabel, 0, len(located))
+
+	for i := range titles {
+		if l, ok := located[i]; ok {
+			labels = append(labels, l)
+		}
+	}
+
+	return labels
+}
+
+// ErrMissingVerticalLabel defines the error of the vertical layout titles not found in the template.
+var ErrMissingVerticalLabel = fmt.Errorf("missing vertical label")
+
+// missingVerticalLabels returns the texts of the titles which are not located as labels.
+func missingVerticalLabels(titles []TitleField, labels []verticalLabel) []string {
+	located := make(map[string]bool, len(labels))
+	for _, l := range labels {
+		located[l.StructField.Name] = true
+	}
+
+	missing := make([]string, 0)
+
+	for _, title := range titles {
+		if !located[title.StructField.Name] {
+			missing = append(missing, title.Title.Text)
+		}
+	}
+
+	return missing
+}
+
+// writeVertical writes the beans in the vertical layout, the labels are located in the template sheet,
+// or written down the column A without a template.
+// ErrMissingVerticalLabel is returned with the missing titles when some labels are not found in the template.
+func (x *Xlsx) writeVertical(r *run, titles []TitleField) error {
+	labels := make([]verticalLabel, 0)
+
+	if x.hasInput() {
+		labels = x.locateVerticalLabels(x.tmplSheet, titles)
+
+		if missing := missingVerticalLabels(titles, labels); len(missing) > 0 {
+			return fmt.Errorf("%s: %w", strings.Join(missing, ", "), ErrMissingVerticalLabel)
+		}
+
+		if x.tmplSheet != x.currentSheet {
+			for _, row := range x.tmplSheet.Rows() {
+				x.copyRow(row, x.currentSheet.Row(row.RowNumber()))
+			}
+		}
+	}
+
+	if len(labels) == 0 {
+		for _, title := range titles {
+			row := x.currentSheet.AddRow()
+			cell := row.Cell("A")
+			cell.SetString(title.Title.Text)
+			x.setCellStyle(cell, r.writeOption.TitleStyle)
+
+			title.Column = "A"
+			labels = append(labels, verticalLabel{TitleField: title, RowNum: row.RowNumber(), ValueColIdx: 1})
+		}
+	}
+
+	beans := []reflect.Value{r.beanValue}
+
+	if r.isSlice {
+		beans = make([]reflect.Value, r.beanValue.Len())
+		for i := range beans {
+			beans[i] = r.elem(i)
+		}
+	}
+
+	for _, l := range labels {
+		first := l.valueCell(x.currentSheet, 0)
+
+		for i, bean := range beans {
+			cell := l.valueCell(x.currentSheet, i)
+			if i > 0 {
+				CopyCellStyle(first, cell)
+			}
+
+			if err := x.setRowCellValue(cell, l.StructField, bean, l.RowNum); err != nil {
+				return err
+			}
+
+			x.setFieldStyle(cell, l.StructField)
+		}
+
+		x.rowWritten(l.RowNum)
+	}
+
+	x.fitVerticalColumnWidths(labels, len(beans), r.writeOption)
+
+	return nil
+}
+
+func (x *Xlsx) fitVerticalColumnWidths(labels []verticalLabel, records int, option WriteOption) {
+	if !option.AutoWidth || len(labels) == 0 {
+		return
+	}
+
+	minRow, maxRow := labels[0].RowNum, labels[0].RowNum
+	columns := make(map[string]bool)
+
+	for _, l := range labels {
+		if l.RowNum < minRow {
+			minRow = l.RowNum
+		}
+
+		if l.RowNum > maxRow {
+			maxRow = l.RowNum
+		}
+
+		columns[l.Column] = true
+
+		for i := 0; i < records; i++ {
+			columns[reference.IndexToColumn(l.ValueColIdx+uint32(i))] = true
+		}
+	}
+
+	for col := range columns {
+		x.fitColumnWidth(col, reflect.StructField{}, option, minRow, maxRow)
+	}
+}
+
+// readVertical reads the bean, or the beans side by side in columns, in the vertical layout.
+func (x *Xlsx) readVertical(r *run, titles []TitleField) error {
+	labels := x.locateVerticalLabels(x.currentSheet, titles)
+	if len(labels) == 0 {
+		return ErrFailToLocationTitleRow
+	}
+
+	if !r.isSlice {
+		if _, err := x.readVerticalRecord(labels, r.beanValue, 0); err != nil {
+			return err
+		}
+
+		x.labelRowsRead(labels)
+
+		return nil
+	}
+
+	slice := reflect.MakeSlice(reflect.SliceOf(r.beanType), 0, 1)
+
+	for i := 0; ; i++ {
+		if err := x.ctxErr(); err != nil {
+			return err
+		}
+
+		bean := reflect.New(r.beanType).Elem()
+
+		empty, err := x.readVerticalRecord(labels, bean, i)
+		if err != nil {
+			return err
+		}
+
+		if empty {
+			break
+		}
+
+		slice = reflect.Append(slice, bean)
+	}
+
+	r.setSlice(slice)
+	x.labelRowsRead(labels)
+
+	return nil
+}
+
+// labelRowsRead calls the RowRead hook with the label rows, which hold the values of all the records.
+func (x *Xlsx) labelRowsRead(labels []verticalLabel) {
+	for _, l := range labels {
+		x.rowRead(l.RowNum)
+	}
+}
+
+// readVerticalRecord reads the record-th record into the bean, and tells whether all the values are empty.
+func (x *Xlsx) readVerticalRecord(labels []verticalLabel, bean reflect.Value, record int) (bool, error) {
+	empty := true
+
+	for _, l := range labels {
+		c := l.valueCell(x.currentSheet, record)
+
+		if isImageField(l.StructField) {
+			found, err := x.setImageField(bean, l.StructField, c.Reference())
+			if err != nil {
+				return false, err
+			}
+
+			empty = empty && !found
+
+			continue
+		}
+
+		s := x.getCellString(c)
+		if s == "" {
+			continue
+		}
+
+		empty = false
+
+		if err := setFieldValue(bean, l.StructField, s); err != nil {
+			x.cellFailed(c.Reference(), err)
+			return false, err
+		}
+	}
+
+	return empty, nil
+}
@@ -0,0 +1,96 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+type device struct {
+	Name   string `title:"设备名称" layout:"vertical"`
+	Serial string `title:"序列号"`
+	Ports  int    `title:"端口数"`
+}
+
+func TestVerticalLayout(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	devices := []device{
+		{Name: "交换机", Serial: "SN001", Ports: 24},
+		{Name: "路由器", Serial: "SN002", Ports: 4},
+	}
+
+	assert.Nil(t, x.Write(devices))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet := wb.Sheets()[0]
+	assert.Equal(t, "设备名称", sheet.Cell("A1").GetString())
+	assert.Equal(t, "交换机", sheet.Cell("B1").GetString())
+	assert.Equal(t, "路由器", sheet.Cell("C1").GetString())
+	assert.Equal(t, "端口数", sheet.Cell("A3").GetString())
+	assert.Equal(t, "4", sheet.Cell("C3").GetFormattedValue())
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var read []device
+
+	assert.Nil(t, x2.Read(&read))
+	assert.Equal(t, devices, read)
+
+	var one device
+
+	assert.Nil(t, x2.Read(&one))
+	assert.Equal(t, devices[0], one)
+}
+
+func TestVerticalLayoutTemplate(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("设备登记表")
+	sheet.Cell("A2").SetString("设备名称：")
+	sheet.AddMergedCells("A2", "B2")
+	sheet.Cell("A3").SetString("序列号")
+	sheet.Cell("D3").SetString("端口数")
+
+	x, _ := xlsx.New(xlsx.WithTemplate(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	assert.Nil(t, x.Write(device{Name: "交换机", Serial: "SN001", Ports: 24}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	sheet = wb.Sheets()[0]
+	assert.Equal(t, "交换机", sheet.Cell("C2").GetString())
+	assert.Equal(t, "SN001", sheet.Cell("B3").GetString())
+	assert.Equal(t, "24", sheet.Cell("E3").GetFormattedValue())
+}
+
+func TestVerticalLayoutTemplateMissingLabels(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("设备名称")
+
+	x, _ := xlsx.New(xlsx.WithTemplate(saveWorkbook(t, wb)))
+	defer x.Close()
+
+	err := x.Write(device{Name: "交换机", Serial: "SN001", Ports: 24})
+	assert.True(t, errors.Is(err, xlsx.ErrMissingVerticalLabel))
+	assert.Contains(t, err.Error(), "序列号, 端口数")
+}
@@ -0,0 +1,134 @@
+package xlsx
+
+import (
+	"reflect"
+	"strconv"
+	"unicode"
+
+	"github.com/unidoc/unioffice/measurement"
+	"github.com/unidoc/unioffice/schema/soo/sml"
+	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
+)
+
+const (
+	// DefaultMinWidth is the default minimum column width in characters when fitting widths.
+	DefaultMinWidth = 8
+	// DefaultMaxWidth is the default maximum column width in characters when fitting widths.
+	DefaultMaxWidth = 60
+)
+
+// WithAutoWidth fits the column widths by the title and data texts.
+// The widths are counted in characters, CJK characters count as double width,
+// and capped between minWidth and maxWidth, zeros mean DefaultMinWidth and DefaultMaxWidth.
+// The tag like `width:"20"` overrides the fitted width of a column.
+func WithAutoWidth(minWidth, maxWidth float64) WriteOptionFn {
+	return func(o *WriteOption) {
+		o.AutoWidth = true
+		o.MinWidth = minWidth
+		o.MaxWidth = maxWidth
+	}
+}
+
+func (x *Xlsx) fitTitledColumnWidths(l templateLocation, option WriteOption) {
+	for _, tf := range l.titleFields {
+		x.fitColumnWidth(tf.Column, tf.StructField, option, l.titledRowNum, l.titledRowNum+x.rowsWritten)
+	}
+}
+
+func (x *Xlsx) fitUntitledColumnWidths(fields []reflect.StructField, option WriteOption, startRow, endRow int) {
+	if startRow <= 0 {
+		return
+	}
+
+	for i, f := range fields {
+		x.fitColumnWidth(reference.IndexToColumn(uint32(i)), f, option, uint32(startRow), uint32(endRow))
+	}
+}
+
+func (x *Xlsx) fitColumnWidth(column string, f reflect.StructField, option WriteOption, startRow, endRow uint32) {
+	width := 0.0
+
+	if v := f.Tag.Get("width"); v != "" {
+		width, _ = strconv.ParseFloat(v, 64)
+	} else if option.AutoWidth {
+		width = x.measureColumn(column, startRow, endRow, option)
+	}
+
+	if width <= 0 {
+		return
+	}
+
+	idx := reference.ColumnToIndex(column)
+	x.currentSheet.Column(idx + 1).SetWidth(measurement.Distance(width) * measurement.Character)
+}
+
+func (x *Xlsx) measureColumn(column string, startRow, endRow uint32, option WriteOption) float64 {
+	minWidth, maxWidth := option.MinWidth, option.MaxWidth
+	if minWidth <= 0 {
+		minWidth = DefaultMinWidth
+	}
+
+	if maxWidth <= 0 {
+		maxWidth = DefaultMaxWidth
+	}
+
+	width := 0
+	for rowNum := startRow; rowNum <= endRow; rowNum++ {
+		if w := TextWidth(cellDisplayText(x.currentSheet.Row(rowNum).Cell(column))); w > width {
+			width = w
+		}
+	}
+
+	// 2 characters for the padding.
+	fitted := float64(width + 2) // nolint:gomnd
+
+	if fitted < minWidth {
+		return minWidth
+	}
+
+	if fitted > maxWidth {
+		return maxWidth
+	}
+
+	return fitted
+}
+
+// cellDisplayText returns the text displayed of the cell, the number format is applied for numbers.
+func cellDisplayText(c spreadsheet.Cell) string {
+	if x := c.X(); x.V != nil && (x.TAttr == sml.ST_CellTypeN || x.TAttr == sml.ST_CellTypeUnset) {
+		return c.GetFormattedValue()
+	}
+
+	return GetCellString(c)
+}
+
+// TextWidth returns the display width of s, where the east asian wide characters count as 2.
+func TextWidth(s string) int {
+	w := 0
+
+	for _, r := range s {
+		if isWideRune(r) {
+			w += 2
+		} else {
+			w++
+		}
+	}
+
+	return w
+}
+
+func isWideRune(r rune) bool {
+	switch {
+	case r < 0x1100:
+		return false
+	case unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana):
+		return true
+	case r >= 0x3000 && r <= 0x303F, // CJK symbols and punctuation
+		r >= 0xFF00 && r <= 0xFF60, // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,50 @@
+package xlsx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingoohuang/xlsx"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
+)
+
+func TestTextWidth(t *testing.T) {
+	assert.Equal(t, 0, xlsx.TextWidth(""))
+	assert.Equal(t, 3, xlsx.TextWidth("abc"))
+	assert.Equal(t, 6, xlsx.TextWidth("中文ab"))
+	assert.Equal(t, 4, xlsx.TextWidth("（）"))
+}
+
+type hostWidth struct {
+	Name   string  `title:"服务器可用区名称"`
+	Remark string  `title:"备注" width:"20"`
+	Amount float64 `title:"金额" style:"money"`
+}
+
+func TestAutoWidth(t *testing.T) {
+	x, _ := xlsx.New(xlsx.WithStyles(map[string]xlsx.Style{"money": {NumberFormat: "#,##0.00"}}))
+	defer x.Close()
+
+	err := x.Write([]hostWidth{
+		{Name: "a", Remark: "b", Amount: 1234567.5},
+	}, xlsx.WithAutoWidth(0, 0))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	widths := map[uint32]float64{}
+
+	for _, cols := range wb.Sheets()[0].X().Cols {
+		for _, c := range cols.Col {
+			widths[c.MinAttr] = *c.WidthAttr
+		}
+	}
+
+	assert.Equal(t, map[uint32]float64{1: 18, 2: 20, 3: 14}, widths)
+}
@@ -1,18 +1,21 @@
 package xlsx
 
 import (
+	"context"
+	"encoding"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/araddon/dateparse"
 	"github.com/bingoohuang/xlsx/pkg/cast"
 	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/formula"
 	"github.com/unidoc/unioffice/spreadsheet/reference"
 )
 
@@ -24,6 +27,32 @@ type Xlsx struct {
 	rowsWritten             uint32
 
 	tmplSheetReused bool
+	// written tells the workbook is filled by Write, which is no longer a template for WriteZip.
+	written bool
+
+	styles       map[Style]uint32
+	mergedStyles map[mergedStyleKey]uint32
+	dateStyles   map[dateStyleKey]uint32
+
+	// timeLayouts are the layouts of the time placeholder vars by the format tags.
+	timeLayouts map[string]string
+
+	readOption   ReadOption
+	evaluator    formula.Evaluator
+	mergedRanges []mergedRange
+	cellImages   map[string][]byte
+
+	// ctx is the context of the current ReadContext or WriteContext.
+	ctx context.Context
+}
+
+// ctxErr returns the error of the context when it is canceled.
+func (x *Xlsx) ctxErr() error {
+	if x.ctx == nil {
+		return nil
+	}
+
+	return x.ctx.Err()
 }
 
 func (x *Xlsx) hasInput() bool {
@@ -36,6 +65,16 @@ func New(optionFns ...OptionFn) (x *Xlsx, err error) {
 
 	x = &Xlsx{option: createOption(optionFns)}
 
+	if err := x.option.err; err != nil {
+		for _, wb := range []*spreadsheet.Workbook{x.option.TemplateWorkbook, x.option.Workbook} {
+			if wb != nil {
+				_ = wb.Close()
+			}
+		}
+
+		return nil, err
+	}
+
 	x.tmplWorkbook = x.option.TemplateWorkbook
 	x.workbook = x.option.Workbook
 
@@ -69,6 +108,15 @@ type run struct {
 	writeOption WriteOption
 	isSlice     bool
 	isPtr       bool
+	// ptrElem tells the slice elements are the pointers to the beans, like []*T.
+	ptrElem bool
+
+	// dynamicField is the map field tagged with `xlsx:"dynamic"`, which expands into extra columns.
+	dynamicField *reflect.StructField
+
+	// rowBeans are the bean indexes of the written rows from the first data row,
+	// -1 for the inserted rows like subtotals.
+	rowBeans []int
 }
 
 func makeRun(beans interface{}, writeOptionFns []WriteOptionFn) *run {
@@ -87,6 +135,10 @@ func makeRun(beans interface{}, writeOptionFns []WriteOptionFn) *run {
 
 	if r.isSlice {
 		r.beanType = r.beanType.Elem()
+
+		if r.ptrElem = r.beanType.Kind() == reflect.Ptr; r.ptrElem {
+			r.beanType = r.beanType.Elem()
+		}
 	}
 
 	r.collectTags()
@@ -143,20 +195,75 @@ func (r *run) collectExportableFields() {
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 
+		if f.PkgPath == "" && isDynamicField(f) {
+			r.dynamicField = &f
+			continue
+		}
+
 		if f.PkgPath == "" && f.Tag.Get("title") != "-" {
 			r.fields = append(r.fields, f)
 		}
 	}
 }
 
+// elem returns the i-th bean of the slice, which is invalid for the nil pointer element.
+func (r *run) elem(i int) reflect.Value {
+	return reflect.Indirect(r.beanValue.Index(i))
+}
+
+// setSlice sets the read slice of the beans, as the slice of the pointers for the pointer elements.
+func (r *run) setSlice(slice reflect.Value) {
+	if r.ptrElem {
+		ptrs := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(r.beanType)), slice.Len(), slice.Len())
+		for i := 0; i < slice.Len(); i++ {
+			ptrs.Index(i).Set(slice.Index(i).Addr())
+		}
+
+		slice = ptrs
+	}
+
+	r.rawValue.Elem().Set(slice)
+}
+
+// ErrNilBean defines the error of the nil pointer element in the slice beans.
+var ErrNilBean = fmt.Errorf("nil bean")
+
+// checkNilBeans rejects the nil pointer elements of the slice beans with the index.
+func (r *run) checkNilBeans() error {
+	if !r.isSlice {
+		return nil
+	}
+
+	for i := 0; i < r.beanValue.Len(); i++ {
+		if !r.elem(i).IsValid() {
+			return fmt.Errorf("index %d: %w", i, ErrNilBean)
+		}
+	}
+
+	return nil
+}
+
 func (r *run) getSingleBean() reflect.Value {
 	if r.isSlice {
-		return r.beanValue.Index(0)
+		return r.elem(0)
 	}
 
 	return r.beanValue
 }
 
+// rowBean returns the bean written at the offset row from the first data row.
+func (r *run) rowBean(offset int) (reflect.Value, bool) {
+	if !r.isSlice {
+		return r.beanValue, offset == 0
+	}
+
+	if offset < 0 || offset >= len(r.rowBeans) || r.rowBeans[offset] < 0 {
+		return reflect.Value{}, false
+	}
+
+	return r.elem(r.rowBeans[offset]), true
+}
+
 func (r *run) forRead() bool {
 	return r.isPtr && (r.isSlice || r.beanType.Kind() == reflect.Struct)
 }
@@ -213,6 +320,25 @@ const (
 type WriteOption struct {
 	SheetName     string
 	MergeColsMode MergeColsMode
+	TitleStyle    Style
+
+	AutoWidth          bool
+	MinWidth, MaxWidth float64
+
+	FreezeTitle bool
+	AutoFilter  bool
+	PrintSetup  *PrintSetup
+
+	TotalsRow   bool
+	TotalsLabel string
+	TotalsStyle Style
+
+	OutlineLevelField string
+	OutlineCollapsed  bool
+
+	Subtotals *Subtotals
+
+	Columns []string
 }
 
 type WriteOptionFn func(*WriteOption)
@@ -229,22 +355,76 @@ func WithSheetName(v string) WriteOptionFn {
 	}
 }
 
+// ReadOption defines the option for reading.
+type ReadOption struct {
+	FormulaMode     FormulaMode
+	MergedCellsFill bool
+
+	SheetName     string
+	TitleRowNum   uint32
+	Titles        []string
+	KeepEmptyRows bool
+}
+
+// ReadOptionFn defines the func to change the read option.
+type ReadOptionFn func(*ReadOption)
+
 // Write Writes beans to the underlying xlsx.
 func (x *Xlsx) Write(beans interface{}, writeOptionFns ...WriteOptionFn) error {
+	return x.WriteContext(context.Background(), beans, writeOptionFns...)
+}
+
+// WriteContext writes beans like Write, and stops with the error of ctx when it is canceled between the rows.
+func (x *Xlsx) WriteContext(ctx context.Context, beans interface{}, writeOptionFns ...WriteOptionFn) (err error) {
+	defer x.phaseDone("write", time.Now(), &err)
+
+	x.ctx = ctx
+	defer func() { x.ctx = nil }()
+
+	x.written = true
+
+	if isMapSlice(beans) {
+		return x.writeMaps(beans, writeOptionFns)
+	}
+
 	r := makeRun(beans, writeOptionFns)
 	if r.isEmptySlice() {
 		return nil
 	}
 
+	if err := r.checkNilBeans(); err != nil {
+		return err
+	}
+
 	x.tmplSheet, x.currentSheet = x.createWriteSheet(x.workbook, r)
 
 	if r.asPlaceholder() {
-		x.writePlaceholder(r.fields, collectPlaceholders(x.currentSheet), r.getSingleBean())
+		x.timeLayouts = placeholderTimeLayouts(r.fields)
+
+		if err := x.checkPlaceholders(r.fields); err != nil {
+			return err
+		}
+
+		if r.isSlice && r.beanValue.Len() > 1 {
+			return x.writePlaceholderSheets(r)
+		}
+
+		vars, err := x.writePlaceholderBean(r.fields, r.getSingleBean())
+		if err != nil {
+			return err
+		}
+
+		x.writeWorkbookPlaceholders(vars)
 
 		return nil
 	}
 
 	titles, customizedTitles := collectTitles(r.fields)
+
+	if r.vertical() {
+		return x.writeVertical(r, titles)
+	}
+
 	_, noTitle := r.LookupTtag("notitle")
 	loc, err := x.locateTitleRow(titles, customizedTitles, noTitle)
 
@@ -256,64 +436,149 @@ func (x *Xlsx) Write(beans interface{}, writeOptionFns ...WriteOptionFn) error {
 	newSheet := x.tmplSheet != x.currentSheet
 
 	if newSheet {
-		copyRowsUtilTitle(location, x.tmplSheet, x.currentSheet)
+		x.copyRowsUtilTitle(location, x.tmplSheet, x.currentSheet)
 	}
 
+	titledRowNum := 0
+
 	if !location.isValid() && !noTitle {
-		x.writeTitles(r.fields, titles)
+		titledRowNum = int(x.writeTitles(r.fields, titles, r.writeOption.TitleStyle))
 	}
 
 	if location.isValid() {
 		x.rowsWritten = 0
 
 		if r.isSlice {
+			sub, err := x.newSubtotaler(r, location.titleFields, func() uint32 {
+				return x.addTemplateRow(location, newSheet).RowNumber()
+			})
+			if err != nil {
+				return err
+			}
+
 			for i := 0; i < r.beanValue.Len(); i++ {
-				x.writeTemplateRow(location, r.beanValue.Index(i), newSheet)
+				if err := x.ctxErr(); err != nil {
+					return err
+				}
+
+				if err := sub.write(i, func(v reflect.Value) (uint32, error) {
+					return x.writeTemplateRow(location, v, newSheet)
+				}); err != nil {
+					return err
+				}
 			}
-		} else {
-			x.writeTemplateRow(location, r.beanValue, newSheet)
+
+			if err := sub.flush(); err != nil {
+				return err
+			}
+		} else if _, err := x.writeTemplateRow(location, r.beanValue, newSheet); err != nil {
+			return err
 		}
 
 		x.removeTempleRows(location)
-		x.mergeTitled(location, r.writeOption)
+		dynamicColumns := x.writeTemplateDynamicColumns(r, location)
+		x.mergeTitled(location, r.writeOption, r)
+		x.outlineRows(r.writeOption, r, int(location.titledRowNum+1))
+
+		dataRow := location.titledRowNum + 1
+		if err := x.writeTotalsRow(location.titleFields, r.writeOption,
+			dataRow, dataRow+x.rowsWritten-1, dataRow+x.rowsWritten); err != nil {
+			return err
+		}
+
+		x.fitTitledColumnWidths(location, r.writeOption)
+		x.fitDynamicColumnWidths(r, dynamicColumns, location.titledRowNum, location.titledRowNum+x.rowsWritten)
+		x.setupSheet(extendRange(titledWrittenRange(location, x.rowsWritten), dynamicColumns), r.writeOption)
+
+		if err := x.createTemplateDataValidations(location, x.currentSheet); err != nil {
+			return err
+		}
 
-		return x.createTemplateDataValidations(location, x.currentSheet)
+		return x.createTemplateConditionalFormats(r, location, x.currentSheet)
 	}
 
+	startRowNum := -1
+	endRowNum := -1
+
 	if r.isSlice {
-		startRowNum := -1
-		endRowNum := -1
+		sub, err := x.newSubtotaler(r, untitledFields(r.fields), func() uint32 {
+			x.rowsWritten++
+			return x.currentSheet.AddRow().RowNumber()
+		})
+		if err != nil {
+			return err
+		}
 
 		for i := 0; i < r.beanValue.Len(); i++ {
-			rowNum := x.writeRow(r.fields, r.beanValue.Index(i))
+			if err := x.ctxErr(); err != nil {
+				return err
+			}
 
-			if i == 0 {
-				startRowNum = int(rowNum)
+			if err := sub.write(i, func(v reflect.Value) (uint32, error) {
+				return x.writeRow(r.fields, v)
+			}); err != nil {
+				return err
 			}
+		}
 
-			endRowNum = int(rowNum)
+		if err := sub.flush(); err != nil {
+			return err
 		}
-		x.mergeRows(r.fields, r.writeOption, startRowNum, endRowNum)
+
+		startRowNum, endRowNum = int(sub.firstRow), int(sub.lastRow)
+		x.mergeRows(r.fields, r.writeOption, r, startRowNum, endRowNum)
+		x.outlineRows(r.writeOption, r, startRowNum)
 	} else {
-		x.writeRow(r.fields, r.beanValue)
+		rowNum, err := x.writeRow(r.fields, r.beanValue)
+		if err != nil {
+			return err
+		}
+
+		startRowNum, endRowNum = int(rowNum), int(rowNum)
 	}
 
-	return x.createDataValidations(r.fields, x.currentSheet)
+	dataRowNum := uint32(startRowNum)
+	dynamicColumns := x.writeDynamicColumns(r, uint32(len(r.fields)),
+		uint32(titledRowNum), uint32(startRowNum), uint32(endRowNum))
+
+	if err := x.writeTotalsRow(untitledFields(r.fields), r.writeOption,
+		uint32(startRowNum), uint32(endRowNum), uint32(endRowNum+1)); err != nil {
+		return err
+	}
+
+	if titledRowNum > 0 {
+		startRowNum = titledRowNum
+	}
+
+	x.fitUntitledColumnWidths(r.fields, r.writeOption, startRowNum, endRowNum)
+	x.fitDynamicColumnWidths(r, dynamicColumns, uint32(startRowNum), uint32(endRowNum))
+	x.setupSheet(extendRange(writtenRange{
+		titledRowNum: uint32(titledRowNum),
+		lastRowNum:   uint32(endRowNum),
+		firstCol:     "A",
+		lastCol:      reference.IndexToColumn(uint32(len(r.fields) - 1)),
+	}, dynamicColumns), r.writeOption)
+
+	if err := x.createDataValidations(r.fields, x.currentSheet); err != nil {
+		return err
+	}
+
+	return x.createConditionalFormats(r, x.currentSheet, dataRowNum)
 }
 
-func copyRowsUtilTitle(location templateLocation, tmplSheet, dataSheet spreadsheet.Sheet) {
+func (x *Xlsx) copyRowsUtilTitle(location templateLocation, tmplSheet, dataSheet spreadsheet.Sheet) {
 	for _, trow := range tmplSheet.Rows() {
 		if trow.RowNumber() > location.titledRowNum {
 			break
 		}
 
 		drow := dataSheet.Row(trow.RowNumber())
-		copyRow(trow, drow)
+		x.copyRow(trow, drow)
 	}
 }
 
-func copyRow(from, to spreadsheet.Row) {
-	for _, f := range RowCells(from) {
+func (x *Xlsx) copyRow(from, to spreadsheet.Row) {
+	for _, f := range x.rowCells(from) {
 		col, _ := f.Column()
 		t := to.Cell(col)
 		t.SetString(GetCellString(f))
@@ -323,38 +588,140 @@ func copyRow(from, to spreadsheet.Row) {
 
 func (x *Xlsx) writePlaceholder(fields []reflect.StructField,
 	plMap map[string]PlaceholderValue, v reflect.Value,
-) {
-	vars := make(map[string]string)
+) error {
+	vars := placeholderVars(fields, v)
 	placeholderCells := make(map[string]string)
 
 	for _, f := range fields {
-		name := f.Tag.Get("placeholder")
-		if name == "" {
-			name = f.Name
+		if c := f.Tag.Get("placeholderCell"); c != "" {
+			placeholderCells[c] = vars[placeholderName(f)]
 		}
+	}
 
-		vars[name] = getFieldValue(f, v)
+	fieldTypes := make(map[string]reflect.StructField)
+	placeholderFieldTypes(fieldTypes, "", fields)
 
-		if v := f.Tag.Get("placeholderCell"); v != "" {
-			placeholderCells[v] = vars[name]
+	for k, pl := range plMap {
+		cell := x.currentSheet.Cell(k)
+
+		if name, ok := wholeCellVar(pl); ok && isImageField(fieldTypes[name]) {
+			cell.SetString("")
+
+			if err := x.setImageCell(cell, fieldTypes[name], placeholderImage(fields, v, name)); err != nil {
+				return err
+			}
+
+			continue
 		}
-	}
 
-	for k, v := range plMap {
-		x.currentSheet.Cell(k).SetString(v.Interpolate(vars))
+		x.setPlaceholderCell(cell, pl, vars, fieldTypes)
 	}
 
 	for k, v := range placeholderCells {
 		x.currentSheet.Cell(k).SetString(v)
 	}
+
+	return nil
 }
 
-func collectPlaceholders(sheet spreadsheet.Sheet) map[string]PlaceholderValue {
+func placeholderVars(fields []reflect.StructField, v reflect.Value) map[string]string {
+	vars := make(map[string]string)
+	addPlaceholderVars(vars, "", fields, v)
+
+	return vars
+}
+
+// addPlaceholderVars adds the vars of the fields with the prefix,
+// the nested struct fields are added by the paths like Owner.Name.
+func addPlaceholderVars(vars map[string]string, prefix string, fields []reflect.StructField, v reflect.Value) {
+	for _, f := range fields {
+		name := prefix + placeholderName(f)
+
+		if nested, ok := nestedStruct(f, v); ok {
+			if nested.IsValid() {
+				addPlaceholderVars(vars, name+".", exportedFields(nested.Type()), nested)
+			}
+
+			// the value types like decimal.Decimal are written by the text too, besides the sub paths.
+			if !isValueStruct(f.Type) {
+				continue
+			}
+
+			if !nested.IsValid() {
+				vars[name] = ""
+				continue
+			}
+		}
+
+		if isImageField(f) {
+			vars[name] = "" // the image is written as a picture instead of the text.
+			continue
+		}
+
+		vars[name] = getFieldValue(f, v)
+	}
+}
+
+// nestedStruct returns the struct value of the field which is a struct or a pointer to struct except time.Time,
+// the returned value is invalid for the nil pointer.
+func nestedStruct(f reflect.StructField, v reflect.Value) (reflect.Value, bool) {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return reflect.Value{}, false
+	}
+
+	return reflect.Indirect(v.FieldByIndex(f.Index)), true
+}
+
+var (
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isValueStruct tells whether the struct (or pointer to struct) type is a value with the text,
+// which implements fmt.Stringer or encoding.TextMarshaler, like decimal.Decimal or sql.NullString.
+func isValueStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PtrTo(t)
+	}
+
+	return t.Implements(stringerType) || t.Implements(textMarshalerType) ||
+		t.Elem().Implements(stringerType) || t.Elem().Implements(textMarshalerType)
+}
+
+func exportedFields(t reflect.Type) []reflect.StructField {
+	fields := make([]reflect.StructField, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// parsePlaceholder parses the placeholders in the content by the delimiters of the option.
+func (x *Xlsx) parsePlaceholder(content string) PlaceholderValue {
+	pl := x.option.PlaceholderDelims.Parse(content)
+	pl.logger = x.logger()
+	pl.filters = x.option.PlaceholderFilters
+	pl.timeLayouts = x.timeLayouts
+
+	return pl
+}
+
+// collectPlaceholders collects the cells with the placeholders or the escaped delimiters to unescape.
+func (x *Xlsx) collectPlaceholders(sheet spreadsheet.Sheet) map[string]PlaceholderValue {
 	placeholders := make(map[string]PlaceholderValue)
 
 	for _, row := range sheet.Rows() {
 		for _, cell := range row.Cells() {
-			if pl := ParsePlaceholder(cell.GetString()); pl.HasPlaceholders() {
+			if pl := x.parsePlaceholder(cell.GetString()); pl.HasPlaceholders() || pl.HasEscapes() {
 				placeholders[cell.Reference()] = pl
 			}
 		}
@@ -379,6 +746,34 @@ func (x *Xlsx) createDataValidations(fields []reflect.StructField, sheet spreads
 	return nil
 }
 
+// createConditionalFormats creates the conditional formats of the fields written from column A,
+// over the data rows from firstRow.
+func (x *Xlsx) createConditionalFormats(r *run, sheet spreadsheet.Sheet, firstRow uint32) error {
+	for i, field := range r.fields {
+		cf := field.Tag.Get("conditionalFormat")
+		refs := r.dataRowRanges(firstRow, reference.IndexToColumn(uint32(i)))
+
+		if err := x.createColumnConditionalFormats(sheet, cf, refs, firstRow); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *Xlsx) createTemplateConditionalFormats(r *run, l templateLocation, sheet spreadsheet.Sheet) error {
+	for _, tc := range l.titleFields {
+		cf := tc.StructField.Tag.Get("conditionalFormat")
+		refs := r.dataRowRanges(l.titledRowNum+1, tc.Column)
+
+		if err := x.createColumnConditionalFormats(sheet, cf, refs, l.titledRowNum+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (x *Xlsx) createTemplateDataValidations(l templateLocation, sheet spreadsheet.Sheet) error {
 	for _, tc := range l.titleFields {
 		dv := tc.StructField.Tag.Get("dataValidation")
@@ -425,15 +820,33 @@ func (x *Xlsx) createColumnDataValidation(startRowNum uint32, sheet spreadsheet.
 
 // Read reads the excel rows to slice.
 // nolint:goerr113
-func (x *Xlsx) Read(slicePtr interface{}) error {
+func (x *Xlsx) Read(slicePtr interface{}, readOptionFns ...ReadOptionFn) error {
+	return x.ReadContext(context.Background(), slicePtr, readOptionFns...)
+}
+
+// ReadContext reads like Read, and stops with the error of ctx when it is canceled between the rows.
+func (x *Xlsx) ReadContext(ctx context.Context, slicePtr interface{}, readOptionFns ...ReadOptionFn) (err error) {
+	defer x.phaseDone("read", time.Now(), &err)
+
+	x.ctx = ctx
+	defer func() { x.ctx = nil }()
+
 	r := makeRun(slicePtr, nil)
 
 	if !r.forRead() {
 		return errors.New("the input argument should be a pointer of slice")
 	}
 
+	x.resetReadOption(readOptionFns)
 	x.tmplSheet = x.createReadSheet(x.tmplWorkbook, r)
 	x.currentSheet = x.createReadSheet(x.workbook, r)
+	x.cellImages = nil
+
+	if x.currentSheet.X() != nil {
+		if err := x.option.Limits.checkSheet(x.currentSheet, x.workbook.SharedStrings); err != nil {
+			return err
+		}
+	}
 
 	if r.asPlaceholder() {
 		err := x.writePlaceholderToBean(r)
@@ -444,34 +857,77 @@ func (x *Xlsx) Read(slicePtr interface{}) error {
 		return nil
 	}
 
-	ignoreEmptyRows := r.ignoreEmptyRows()
+	ignoreEmptyRows := r.ignoreEmptyRows() && !x.readOption.KeepEmptyRows
 
 	titles, customizedTitle := collectTitles(r.fields)
-	loc, err := x.locateTitleRow(titles, customizedTitle, false)
+
+	if r.vertical() {
+		return x.readVertical(r, titles)
+	}
+
+	loc, err := x.locateReadTitleRow(titles, customizedTitle)
 	if err != nil {
 		return err
 	}
 
 	location := *loc
+	x.locateDynamicColumns(&location, r.dynamicField)
+
 	if location.isValid() {
 		slice, err := x.readRows(r.beanType, location, ignoreEmptyRows)
 		if err != nil {
 			return err
 		}
 
-		r.rawValue.Elem().Set(slice)
+		r.setSlice(slice)
 	}
 
 	return nil
 }
 
+func (x *Xlsx) resetReadOption(readOptionFns []ReadOptionFn) {
+	x.readOption = ReadOption{}
+	x.evaluator = nil
+	x.mergedRanges = nil
+
+	for _, fn := range readOptionFns {
+		fn(&x.readOption)
+	}
+}
+
 func (x *Xlsx) writePlaceholderToBean(r *run) error {
-	vars := x.readPlaceholderValues()
+	x.timeLayouts = placeholderTimeLayouts(r.fields)
+
+	if err := x.checkPlaceholders(r.fields); err != nil {
+		return err
+	}
+
 	vv := r.beanValue
+	loops := x.collectPlaceholderLoops(x.tmplSheet)
+	blocks := x.collectPlaceholderBlocks(x.tmplSheet)
+
+	shifts, err := x.readPlaceholderLoops(loops, blocks, r.fields, vv)
+	if err != nil {
+		return err
+	}
+
+	fieldTypes := make(map[string]reflect.StructField)
+	placeholderFieldTypes(fieldTypes, "", r.fields)
+	varSet, err := x.readPlaceholderValues(loops, shifts, fieldTypes)
+	if err != nil {
+		return err
+	}
+
+	if err := x.readWorkbookPlaceholders(varSet); err != nil {
+		return err
+	}
+
+	vars := varSet.vars
+	readBlockVars(blocks, shifts, fieldTypes, vars)
 
 	for _, f := range r.fields {
 		if v := f.Tag.Get("placeholderCell"); v != "" {
-			vs := GetCellString(x.currentSheet.Cell(v))
+			vs := x.getCellString(x.currentSheet.Cell(v))
 			if err := setFieldValue(vv, f, vs); err != nil {
 				return err
 			}
@@ -479,25 +935,102 @@ func (x *Xlsx) writePlaceholderToBean(r *run) error {
 			continue
 		}
 
-		name := f.Tag.Get("placeholder")
-		if name == "" {
-			name = f.Name
+		if isImageField(f) {
+			if err := x.readPlaceholderImage(vv, f, shifts); err != nil {
+				return err
+			}
+
+			continue
 		}
 
+		if err := setPlaceholderVar(vv, f, "", vars); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setPlaceholderVar sets the field value from the vars, the nested struct fields are set by the paths like Owner.Name.
+func setPlaceholderVar(v reflect.Value, f reflect.StructField, prefix string, vars map[string]string) error {
+	name := prefix + placeholderName(f)
+
+	if _, ok := nestedStruct(f, v); !ok {
 		if varValue, ok := vars[name]; ok {
-			if err := setFieldValue(vv, f, varValue); err != nil {
-				return err
-			}
+			return setFieldValue(v, f, varValue)
+		}
+
+		return nil
+	} else if varValue, ok := vars[name]; ok && isValueStruct(f.Type) {
+		return setValueStruct(v.FieldByIndex(f.Index), f, varValue)
+	}
+
+	if !hasVarPrefix(vars, name+".") {
+		return nil
+	}
+
+	fv := v.FieldByIndex(f.Index)
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(f.Type.Elem()))
+		}
+
+		fv = fv.Elem()
+	}
+
+	for _, nf := range exportedFields(fv.Type()) {
+		if err := setPlaceholderVar(fv, nf, name+".", vars); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// setValueStruct sets the value struct field by the text, with encoding.TextUnmarshaler if implemented.
+func setValueStruct(fv reflect.Value, f reflect.StructField, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		if s == "" {
+			return nil
+		}
+
+		if fv.IsNil() {
+			fv.Set(reflect.New(f.Type.Elem()))
+		}
+
+		fv = fv.Elem()
+	}
+
+	u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		return fmt.Errorf("unable to set %s by the text %q: %w", f.Name, s, ErrUnsupportedValueStruct)
+	}
+
+	return u.UnmarshalText([]byte(s))
+}
+
+// ErrUnsupportedValueStruct defines the error of reading the text into a struct without encoding.TextUnmarshaler.
+var ErrUnsupportedValueStruct = fmt.Errorf("unsupported value struct")
+
+// hasVarPrefix tells whether any non-empty var has the prefix.
+func hasVarPrefix(vars map[string]string, prefix string) bool {
+	for k, v := range vars {
+		if v != "" && strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (x *Xlsx) readRows(beanType reflect.Type, l templateLocation, ignoreEmptyRows bool) (reflect.Value, error) {
 	slice := reflect.MakeSlice(reflect.SliceOf(beanType), 0, len(l.templateRows))
 
 	for _, row := range l.templateRows {
+		if err := x.ctxErr(); err != nil {
+			return reflect.Value{}, err
+		}
+
 		rowBean, err := x.createRowBean(beanType, l, row, ignoreEmptyRows)
 		if err != nil {
 			return reflect.Value{}, err
@@ -505,6 +1038,7 @@ func (x *Xlsx) readRows(beanType reflect.Type, l templateLocation, ignoreEmptyRo
 
 		if rowBean.IsValid() {
 			slice = reflect.Append(slice, rowBean)
+			x.rowRead(row.RowNumber())
 		}
 	}
 
@@ -524,25 +1058,54 @@ func (x *Xlsx) createRowBean(beanType reflect.Type, l templateLocation,
 
 	for i, cell := range l.titleFields {
 		c := row.Cell(cell.Column)
-		s := GetCellString(c)
+		s := x.getCellString(c)
 		values[i] = templateCellValue{
 			TitleField: cell,
 			value:      s,
 		}
 
-		if ignoreEmptyRows && s == "" {
+		if ignoreEmptyRows && s == "" && !(isImageField(cell.StructField) && x.cellImage(c.Reference()) != nil) {
 			emptyCells++
 		}
 	}
 
-	if emptyCells == len(l.titleFields) {
+	var dynamicValues reflect.Value
+
+	if l.dynamicField != nil {
+		m, emptyDynamicCells, err := x.readDynamicValues(l, row)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		dynamicValues = m
+
+		if ignoreEmptyRows {
+			emptyCells += emptyDynamicCells
+		}
+	}
+
+	if emptyCells == len(l.titleFields)+len(l.dynamicColumns) {
 		return reflect.Value{}, nil
 	}
 
 	rowBean := reflect.New(beanType).Elem()
 
+	if dynamicValues.IsValid() {
+		rowBean.FieldByIndex(l.dynamicField.Index).Set(dynamicValues)
+	}
+
 	for _, cell := range values {
+		if isImageField(cell.StructField) {
+			ref := cell.Column + strconv.Itoa(int(row.RowNumber()))
+			if _, err := x.setImageField(rowBean, cell.StructField, ref); err != nil {
+				return reflect.Value{}, err
+			}
+
+			continue
+		}
+
 		if err := setFieldValue(rowBean, cell.StructField, cell.value); err != nil {
+			x.cellFailed(cell.Column+strconv.Itoa(int(row.RowNumber())), err)
 			return reflect.Value{}, err
 		}
 	}
@@ -611,13 +1174,20 @@ func (x *Xlsx) createWriteSheet(wb *spreadsheet.Workbook, r *run) (tmplSheet, da
 }
 
 func (x *Xlsx) createReadSheet(wb *spreadsheet.Workbook, r *run) spreadsheet.Sheet {
+	return x.findReadSheet(wb, r.FindTtag("sheet"))
+}
+
+// findReadSheet finds the sheet to read by the sheet name, which is overridden by the WithReadSheet option.
+func (x *Xlsx) findReadSheet(wb *spreadsheet.Workbook, sheetName string) spreadsheet.Sheet {
 	wbSheet := spreadsheet.Sheet{}
 
 	if wb == nil {
 		return wbSheet
 	}
 
-	sheetName := r.FindTtag("sheet")
+	if x.readOption.SheetName != "" {
+		sheetName = x.readOption.SheetName
+	}
 
 	if x.hasInput() {
 		if sh := x.findSheet(wb, sheetName); sh.IsValid() {
@@ -686,20 +1256,35 @@ func collectTitles(fields []reflect.StructField) ([]TitleField, bool) {
 }
 
 // SaveToFile writes the workbook out to a file.
-func (x *Xlsx) SaveToFile(file string) error { return x.workbook.SaveToFile(file) }
+func (x *Xlsx) SaveToFile(file string) (err error) {
+	defer x.phaseDone("save", time.Now(), &err)
+
+	return x.workbook.SaveToFile(file)
+}
 
 // Save writes the workbook out to a writer in the zipped xlsx format.
-func (x *Xlsx) Save(w io.Writer) error { return x.workbook.Save(w) }
+func (x *Xlsx) Save(w io.Writer) (err error) {
+	defer x.phaseDone("save", time.Now(), &err)
+
+	return x.workbook.Save(w)
+}
 
-func (x *Xlsx) writeRow(fields []reflect.StructField, value reflect.Value) uint32 {
+func (x *Xlsx) writeRow(fields []reflect.StructField, value reflect.Value) (uint32, error) {
 	row := x.currentSheet.AddRow()
 	x.rowsWritten++
 
 	for _, field := range fields {
-		setCellValue(row.AddCell(), field, value)
+		cell := row.AddCell()
+		if err := x.setRowCellValue(cell, field, value, row.RowNumber()); err != nil {
+			return 0, err
+		}
+
+		x.setFieldStyle(cell, field)
 	}
 
-	return row.RowNumber()
+	x.rowWritten(row.RowNumber())
+
+	return row.RowNumber(), nil
 }
 
 func getFieldValue(field reflect.StructField, value reflect.Value) string {
@@ -720,8 +1305,10 @@ func getFieldValue(field reflect.StructField, value reflect.Value) string {
 }
 
 func setCellValue(cell spreadsheet.Cell, field reflect.StructField, value reflect.Value) {
-	v := value.FieldByIndex(field.Index).Interface()
+	setCellInterface(cell, field.Tag, value.FieldByIndex(field.Index).Interface())
+}
 
+func setCellInterface(cell spreadsheet.Cell, tag reflect.StructTag, v interface{}) {
 	if fv, ok := ConvertNumberToFloat64(v); ok {
 		cell.SetNumber(fv)
 		return
@@ -729,21 +1316,35 @@ func setCellValue(cell spreadsheet.Cell, field reflect.StructField, value reflec
 
 	switch fv := v.(type) {
 	case time.Time:
-		cell.SetString(formatTime(field.Tag, fv))
+		cell.SetString(formatTime(tag, fv))
 	case string:
 		cell.SetString(fv)
 	case bool:
 		cell.SetBool(fv)
 	case nil:
 		cell.SetString("")
+	default:
+		cell.SetString(fmt.Sprint(fv))
 	}
 }
 
-func (x *Xlsx) writeTitles(fields []reflect.StructField, titles []TitleField) {
+func (x *Xlsx) writeTitles(fields []reflect.StructField, titles []TitleField, style Style) uint32 {
 	row := x.currentSheet.AddRow()
 
 	for i := range fields {
-		row.AddCell().SetString(titles[i].Title.Text)
+		cell := row.AddCell()
+		cell.SetString(titles[i].Title.Text)
+		x.setCellStyle(cell, style)
+	}
+
+	return row.RowNumber()
+}
+
+// setFieldStyle merges the named style of the style tag over the existing style of the cell,
+// like the style copied from the template row.
+func (x *Xlsx) setFieldStyle(cell spreadsheet.Cell, field reflect.StructField) {
+	if s, ok := x.lookupStyle(field.Tag.Get("style")); ok {
+		x.mergeCellStyle(cell, s)
 	}
 }
 
@@ -751,6 +1352,9 @@ type templateLocation struct {
 	titleFields  []TitleField
 	templateRows []spreadsheet.Row
 	titledRowNum uint32
+
+	dynamicField   *reflect.StructField
+	dynamicColumns []dynamicColumn
 }
 
 func (t *templateLocation) isValid() bool {
@@ -767,13 +1371,26 @@ func (x *Xlsx) locateTitleRow(titles []TitleField, customizedTitle bool, noTitle
 		return &templateLocation{}, ErrNoExcelRead
 	}
 
-	tmplSheet := x.tmplSheet
-	if !tmplSheet.IsValid() {
-		tmplSheet = x.currentSheet
+	sheet := x.titleSheet()
+	rows := sheet.Rows()
+
+	return x.locateTitleRowIn(titles, customizedTitle, rows, rows)
+}
+
+// titleSheet returns the sheet to locate the title row in, the template sheet if any.
+func (x *Xlsx) titleSheet() spreadsheet.Sheet {
+	if x.tmplSheet.IsValid() {
+		return x.tmplSheet
 	}
 
-	rows := tmplSheet.Rows()
-	titledRowNum, err := x.findTitledRow(titles, customizedTitle, rows)
+	return x.currentSheet
+}
+
+// locateTitleRowIn locates the title row among the titleRows, and the template rows after it among the rows.
+func (x *Xlsx) locateTitleRowIn(titles []TitleField, customizedTitle bool,
+	titleRows, rows []spreadsheet.Row,
+) (*templateLocation, error) {
+	titledRowNum, err := x.findTitledRow(titles, customizedTitle, titleRows)
 	if err != nil {
 		return nil, ErrFailToLocationTitleRow
 	}
@@ -796,7 +1413,7 @@ func (x *Xlsx) findTitledRow(titles []TitleField, customizedTitle bool, rows []s
 
 		found := false
 
-		for _, cell := range RowCells(row) {
+		for _, cell := range x.rowCells(row) {
 			cellString := GetCellString(cell)
 			if cellString == "" {
 				continue
@@ -809,7 +1426,7 @@ func (x *Xlsx) findTitledRow(titles []TitleField, customizedTitle bool, rows []s
 
 				col, err := cell.Column()
 				if err != nil {
-					log.Printf("W! failed to get column error: %v", err)
+					x.logger().Warn("failed to get column", "cell", cell.Reference(), "error", err)
 					continue
 				}
 
@@ -856,17 +1473,37 @@ func (x *Xlsx) findTemplateRows(titledRowNum uint32, rows []spreadsheet.Row) []s
 	return templateRows
 }
 
-func (x *Xlsx) writeTemplateRow(l templateLocation, v reflect.Value, newSheet bool) {
+func (x *Xlsx) writeTemplateRow(l templateLocation, v reflect.Value, newSheet bool) (uint32, error) {
 	// 2 是为了计算row num(1-N), 从标题行(T)的下一行（T+1)开始写
 	num := l.titledRowNum + 1 + x.rowsWritten
 	x.rowsWritten++
 	row := x.currentSheet.Row(num)
 
 	for _, tc := range l.titleFields {
-		setCellValue(row.Cell(tc.Column), tc.StructField, v)
+		if err := x.setRowCellValue(row.Cell(tc.Column), tc.StructField, v, num); err != nil {
+			return 0, err
+		}
+	}
+
+	x.copyRowStyle(l, row, newSheet)
+
+	for _, tc := range l.titleFields {
+		x.setFieldStyle(row.Cell(tc.Column), tc.StructField)
 	}
 
+	x.rowWritten(num)
+
+	return num, nil
+}
+
+// addTemplateRow adds a row after the written rows with the template row style, like the subtotal row.
+func (x *Xlsx) addTemplateRow(l templateLocation, newSheet bool) spreadsheet.Row {
+	num := l.titledRowNum + 1 + x.rowsWritten
+	x.rowsWritten++
+	row := x.currentSheet.Row(num)
 	x.copyRowStyle(l, row, newSheet)
+
+	return row
 }
 
 func (x *Xlsx) copyRowStyle(l templateLocation, row spreadsheet.Row, newSheet bool) {
@@ -906,26 +1543,47 @@ func (x *Xlsx) findSheet(wb *spreadsheet.Workbook, sheetName string) spreadsheet
 	return spreadsheet.Sheet{}
 }
 
-func (x *Xlsx) readPlaceholderValues() map[string]string {
-	plMap := collectPlaceholders(x.tmplSheet)
-	plVars := make(map[string]string)
+func (x *Xlsx) readPlaceholderValues(loops []placeholderLoop, shifts loopRowShifts,
+	fieldTypes map[string]reflect.StructField,
+) (*placeholderVarSet, error) {
+	plMap := x.collectPlaceholders(x.tmplSheet)
+	varSet := newPlaceholderVarSet()
+	refs := make([]string, 0, len(plMap))
 
-	for k, v := range plMap {
-		cellValue := GetCellString(x.currentSheet.Cell(k))
+	for k := range plMap {
+		refs = append(refs, k)
+	}
 
-		if vars, ok := v.ParseVars(cellValue); ok {
-			for vk, vv := range vars {
-				plVars[vk] = vv
-			}
-		} else {
-			log.Printf("W! failed to parse vars from cell value %s by Part %+v", cellValue, v.Content)
+	sort.Strings(refs)
+
+	for _, k := range refs {
+		ref, err := reference.ParseCellReference(k)
+		if err == nil && (isLoopRow(loops, ref.RowIdx) || shifts.removes(ref.RowIdx)) {
+			continue
+		}
+
+		pl := x.removeBlockMarkers(plMap[k])
+		if !pl.HasPlaceholders() {
+			continue
+		}
+
+		dataRef := shifts.shiftRef(k)
+		cellValue := x.placeholderCellString(x.currentSheet.Cell(dataRef), pl, fieldTypes)
+
+		vars, err := pl.Match(cellValue)
+		if err != nil {
+			return nil, fmt.Errorf("cell %s: %w", dataRef, err)
+		}
+
+		if err := varSet.merge(vars, "cell "+dataRef); err != nil {
+			return nil, err
 		}
 	}
 
-	return plVars
+	return varSet, nil
 }
 
-func (x *Xlsx) mergeTitled(l templateLocation, option WriteOption) {
+func (x *Xlsx) mergeTitled(l templateLocation, option WriteOption, r *run) {
 	if x.rowsWritten <= 1 { // there is no need to mergeTitled for one row.
 		return
 	}
@@ -934,49 +1592,12 @@ func (x *Xlsx) mergeTitled(l templateLocation, option WriteOption) {
 	case DoNotMerge:
 		// nothing to do
 	case MergeCols, MergeColsAlign:
-		x.mergeCols(l, option.MergeColsMode)
+		startRow := int(l.titledRowNum + 1)
+		x.mergeColumns(l.titleFields, r, startRow, startRow+int(x.rowsWritten)-1, option.MergeColsMode)
 	}
 }
 
-func (x *Xlsx) mergeCols(l templateLocation, mode MergeColsMode) {
-	alignRowNums := make([]int, 0)
-
-	for _, tf := range l.titleFields {
-		var i uint32 = 1
-
-		startCellString := ""
-		startCell := spreadsheet.Cell{}
-		startRowNum := 0
-		lastCell := spreadsheet.Cell{}
-
-		for ; i <= x.rowsWritten; i++ {
-			rowNum := l.titledRowNum + i
-			cell := x.currentSheet.Row(rowNum).Cell(tf.Column)
-			cs := GetCellString(cell)
-			if cs == startCellString && !reachAlignRowNum(alignRowNums, startRowNum, int(rowNum), mode) {
-				lastCell = cell
-				continue
-			}
-
-			if startCellString != "" && lastCell.X() != nil {
-				x.currentSheet.AddMergedCells(startCell.Reference(), lastCell.Reference())
-
-				alignRowNums = addAlignRowNum(alignRowNums, int(rowNum-1), mode)
-			}
-
-			startCellString = cs
-			startCell = cell
-			startRowNum = int(rowNum)
-			lastCell = spreadsheet.Cell{}
-		}
-
-		if startCellString != "" && lastCell.X() != nil {
-			x.currentSheet.AddMergedCells(startCell.Reference(), lastCell.Reference())
-		}
-	}
-}
-
-func (x *Xlsx) mergeRows(fields []reflect.StructField, option WriteOption, startRowNum, endRowNum int) {
+func (x *Xlsx) mergeRows(fields []reflect.StructField, option WriteOption, r *run, startRowNum, endRowNum int) {
 	if endRowNum-startRowNum <= 1 {
 		return
 	}
@@ -985,22 +1606,41 @@ func (x *Xlsx) mergeRows(fields []reflect.StructField, option WriteOption, start
 	case DoNotMerge:
 		// nothing to do
 	case MergeCols, MergeColsAlign:
-		x.mergeUntitledCols(fields, startRowNum, endRowNum, option.MergeColsMode)
+		x.mergeColumns(untitledFields(fields), r, startRowNum, endRowNum, option.MergeColsMode)
 	}
 }
 
-func (x *Xlsx) mergeUntitledCols(fields []reflect.StructField, startRow, endRow int, mode MergeColsMode) {
+// mergeColumns merges the adjacent equal cells of the columns from startRow to endRow.
+// When any field is tagged with merge:"true" or mergeKey, only the tagged columns are merged.
+// The column tagged with mergeKey:"Field" merges the rows with the equal values of the Field in beans,
+// instead of the equal cell texts.
+func (x *Xlsx) mergeColumns(tfs []TitleField, r *run, startRow, endRow int, mode MergeColsMode) {
 	alignRowNums := make([]int, 0)
+	restricted := hasMergeTags(tfs)
+
+	for _, tf := range tfs {
+		keyField, keyed := mergeKeyField(tf.StructField, r.beanType)
+		if restricted && !keyed && !ParseBool(tf.StructField.Tag.Get("merge"), false) {
+			continue
+		}
 
-	for i := range fields {
 		startCellString := ""
 		startCell := spreadsheet.Cell{}
 		startRowNum := 0
 		lastCell := spreadsheet.Cell{}
 
 		for rowNum := startRow; rowNum <= endRow; rowNum++ {
-			cell := x.currentSheet.Row(uint32(rowNum)).Cell(reference.IndexToColumn(uint32(i)))
-			cs := GetCellString(cell)
+			cell := x.currentSheet.Row(uint32(rowNum)).Cell(tf.Column)
+			cs := ""
+
+			if keyed {
+				if bean, ok := r.rowBean(rowNum - startRow); ok {
+					cs = getFieldValue(keyField, bean)
+				}
+			} else {
+				cs = GetCellString(cell)
+			}
+
 			if cs == startCellString && !reachAlignRowNum(alignRowNums, startRowNum, rowNum, mode) {
 				lastCell = cell
 				continue
@@ -1023,6 +1663,30 @@ func (x *Xlsx) mergeUntitledCols(fields []reflect.StructField, startRow, endRow
 	}
 }
 
+func hasMergeTags(tfs []TitleField) bool {
+	for _, tf := range tfs {
+		if _, ok := tf.StructField.Tag.Lookup("merge"); ok {
+			return true
+		}
+
+		if _, ok := tf.StructField.Tag.Lookup("mergeKey"); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeKeyField finds the key field named by the mergeKey tag of f in the bean type.
+func mergeKeyField(f reflect.StructField, beanType reflect.Type) (reflect.StructField, bool) {
+	key := f.Tag.Get("mergeKey")
+	if key == "" {
+		return reflect.StructField{}, false
+	}
+
+	return beanType.FieldByName(key)
+}
+
 func addAlignRowNum(alignRowNums []int, num int, mode MergeColsMode) []int {
 	if mode == MergeColsAlign {
 		alignRowNums = append(alignRowNums, num)
@@ -1081,7 +1745,7 @@ func formatTime(tag reflect.StructTag, t time.Time) string {
 		return t.Format(ParseJavaTimeFormat(v))
 	}
 
-	return t.Format("2006-01-02 15:04:05")
+	return t.Format(defaultTimeLayout)
 }
 
 func parseTime(tag reflect.StructTag, s string) (time.Time, error) {
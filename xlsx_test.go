@@ -1,6 +1,7 @@
 package xlsx_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/bingoohuang/xlsx"
 	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/spreadsheet"
 )
 
 // ReadBytes reads bytes from the file.
@@ -150,6 +152,42 @@ func TestMergeAlign(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+type orderItem struct {
+	OrderID  string  `title:"订单号" merge:"true"`
+	Customer string  `title:"客户" mergeKey:"OrderID"`
+	Product  string  `title:"商品"`
+	Amount   float64 `title:"金额"`
+}
+
+func TestMergeKey(t *testing.T) {
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	err := x.Write([]orderItem{
+		{OrderID: "O1", Customer: "张三", Product: "P1", Amount: 10},
+		{OrderID: "O1", Customer: "张三", Product: "P1", Amount: 10},
+		{OrderID: "O2", Customer: "张三", Product: "P2", Amount: 10},
+		{OrderID: "O3", Customer: "李四", Product: "P2", Amount: 20},
+	}, xlsx.WithMergeColsMode(xlsx.MergeCols))
+	assert.Nil(t, err)
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	wb, err := spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+
+	refs := make([]string, 0)
+
+	for _, mc := range wb.Sheets()[0].MergedCells() {
+		refs = append(refs, mc.Reference())
+	}
+
+	// the equal amounts and products are not merged, the customer is merged by the order id.
+	assert.Equal(t, []string{"A2:A3", "B2:B3"}, refs)
+}
+
 func TestWithTemplateMerge(t *testing.T) {
 	x, _ := xlsx.New(xlsx.WithTemplate("testdata/template.xlsx"))
 	defer x.Close()
@@ -483,3 +521,29 @@ func TestLocationError(t *testing.T) {
 	err = x.Read(&dataImports)
 	assert.True(t, errors.Is(err, xlsx.ErrFailToLocationTitleRow))
 }
+
+func TestWritePointerBeans(t *testing.T) {
+	type item struct {
+		Name string `title:"名称"`
+		Num  int    `title:"数量"`
+	}
+
+	x, _ := xlsx.New()
+	defer x.Close()
+
+	assert.Nil(t, x.Write([]*item{{Name: "a", Num: 1}, {Name: "b", Num: 2}}))
+
+	var buf bytes.Buffer
+
+	assert.Nil(t, x.Save(&buf))
+
+	x2, _ := xlsx.New(xlsx.WithExcel(buf.Bytes()))
+	defer x2.Close()
+
+	var items []*item
+
+	assert.Nil(t, x2.Read(&items))
+	assert.Equal(t, []*item{{Name: "a", Num: 1}, {Name: "b", Num: 2}}, items)
+
+	assert.True(t, errors.Is(x.Write([]*item{nil}), xlsx.ErrNilBean))
+}